@@ -21,6 +21,12 @@ type boundaryBackend struct {
 	*framework.Backend
 	lock   sync.RWMutex
 	client *boundaryClient
+
+	// rotationLock serialises changes to the root credential
+	rotationLock sync.Mutex
+
+	// staticRoleLock serialises changes to static roles
+	staticRoleLock sync.Mutex
 }
 
 func backend() *boundaryBackend {
@@ -35,18 +41,27 @@ func backend() *boundaryBackend {
 			SealWrapStorage: []string{
 				"config",
 				"role/*",
+				staticRoleStoragePrefix + "*",
 			},
 		},
 		Paths: framework.PathAppend(
 			pathRole(&b),
+			pathStaticRole(&b),
+			pathWorkerCA(&b),
 			[]*framework.Path{
 				pathConfig(&b),
+				pathConfigRotateRoot(&b),
 				pathCredentials(&b),
+				pathAuthorizeSession(&b),
+				pathWorkerRegister(&b),
 			},
 		),
-		Secrets:     []*framework.Secret{b.boundaryAccount(), b.boundaryWorker()}, // Add boundary users secrets generation here.
-		BackendType: logical.TypeLogical,
-		Invalidate:  b.invalidate,
+		Secrets:           []*framework.Secret{b.boundaryAccount(), b.boundaryWorker(), b.boundaryScope()}, // Add boundary users secrets generation here.
+		BackendType:       logical.TypeLogical,
+		Invalidate:        b.invalidate,
+		WALRollback:       b.walRollback,
+		WALRollbackMinAge: minRollbackAge,
+		PeriodicFunc:      b.periodicFunc,
 	}
 	return &b
 }
@@ -2,15 +2,21 @@ package boundarysecrets
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/hashicorp/boundary/api/accounts"
+	"github.com/hashicorp/boundary/api/authmethods"
+	"github.com/hashicorp/boundary/api/authtokens"
+	"github.com/hashicorp/boundary/api/groups"
 	"github.com/hashicorp/boundary/api/roles"
+	"github.com/hashicorp/boundary/api/sessions"
 	"github.com/hashicorp/boundary/api/users"
 	"github.com/hashicorp/boundary/api/workers"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/sethvargo/go-password/password"
-	"log"
 	"strings"
 	"time"
 )
@@ -18,22 +24,35 @@ import (
 const (
 	Account = "account"
 	Worker  = "worker"
+	Scope   = "scope"
 )
 
 type boundaryAccount struct {
-	AccountId     string `json:"account_id"`
-	AuthMethodId  string `json:"auth_method_id"`
-	LoginName     string `json:"login_name"`
-	Password      string `json:"password"`
-	BoundaryRoles string `json:"boundary_roles"`
-	UserId        string `json:"user_id"`
+	AccountId      string   `json:"account_id"`
+	AuthMethodId   string   `json:"auth_method_id"`
+	LoginName      string   `json:"login_name"`
+	Password       string   `json:"password"`
+	BoundaryRoles  []string `json:"boundary_roles"`
+	BoundaryGroups []string `json:"boundary_groups"`
+	UserId         string   `json:"user_id"`
+
+	// EphemeralRoleId is the Boundary role created for this
+	// account from the Vault role's grant strings, if any.
+	EphemeralRoleId string `json:"ephemeral_role_id"`
+
+	// The auth token issued to the account, for roles with
+	// the auth_token credential type.
+	AuthTokenId         string    `json:"auth_token_id"`
+	AuthToken           string    `json:"auth_token"`
+	AuthTokenExpiration time.Time `json:"auth_token_expiration"`
 }
 
 type boundaryWorker struct {
-	WorkerId        string `json:"worker_id"`
-	ActivationToken string `json:"activation_token"`
-	Description     string `json:"description"`
-	WorkerName      string `json:"worker_name"`
+	WorkerId        string              `json:"worker_id"`
+	ActivationToken string              `json:"activation_token"`
+	Description     string              `json:"description"`
+	WorkerName      string              `json:"worker_name"`
+	WorkerTags      map[string][]string `json:"worker_tags"`
 }
 
 func (b *boundaryBackend) boundaryAccount() *framework.Secret {
@@ -62,10 +81,30 @@ func (b *boundaryBackend) boundaryAccount() *framework.Secret {
 				Type:        framework.TypeString,
 				Description: "Boundary User ID",
 			},
+			"boundary_groups": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "List of Boundary groups the Account's user belongs to",
+			},
 			"boundary_roles": {
-				Type:        framework.TypeString,
+				Type:        framework.TypeCommaStringSlice,
 				Description: "List of Boundary roles assigned to the Account",
 			},
+			"ephemeral_role_id": {
+				Type:        framework.TypeString,
+				Description: "ID of the Boundary role created for the Account from the role's grant strings",
+			},
+			"auth_token_id": {
+				Type:        framework.TypeString,
+				Description: "ID of the Boundary auth token issued to the Account",
+			},
+			"auth_token": {
+				Type:        framework.TypeString,
+				Description: "Boundary auth token issued to the Account",
+			},
+			"auth_token_expiration": {
+				Type:        framework.TypeString,
+				Description: "Expiration time of the Boundary auth token",
+			},
 		},
 	}
 }
@@ -92,6 +131,10 @@ func (b *boundaryBackend) boundaryWorker() *framework.Secret {
 				Type:        framework.TypeString,
 				Description: "Description of Boundary worker",
 			},
+			"worker_tags": {
+				Type:        framework.TypeMap,
+				Description: "Tags added to the Boundary worker",
+			},
 		},
 	}
 }
@@ -121,7 +164,49 @@ func (b *boundaryBackend) accountRevoke(ctx context.Context, req *logical.Reques
 		}
 	}
 
-	if err := deleteToken(ctx, client, accountId, userId); err != nil {
+	ephemeralRoleId := ""
+	ephemeralRoleIdRaw, ok := req.Secret.InternalData["ephemeral_role_id"]
+	if ok {
+		ephemeralRoleId, ok = ephemeralRoleIdRaw.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for ephemeral_role_id in secret internal data")
+		}
+	}
+
+	authTokenId := ""
+	authTokenIdRaw, ok := req.Secret.InternalData["auth_token_id"]
+	if ok {
+		authTokenId, ok = authTokenIdRaw.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for auth_token_id in secret internal data")
+		}
+	}
+
+	var groupIds []string
+	if groupIdsRaw, ok := req.Secret.InternalData["boundary_groups"]; ok && groupIdsRaw != nil {
+		groupIds, err = parseutil.ParseCommaStringSlice(groupIdsRaw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for boundary_groups in secret internal data: %w", err)
+		}
+	}
+
+	// Sessions outlive the user that created them, so stop them first
+	cancelled, err := cancelUserSessions(ctx, client, userId)
+	if err != nil {
+		return nil, fmt.Errorf("error cancelling sessions: %w", err)
+	}
+	b.Logger().Info("cancelled sessions for revoked user", "user_id", userId, "sessions", cancelled)
+
+	// Leave the groups explicitly rather than relying on the user
+	// deletion, so that each group's history records the removal
+	for _, groupId := range groupIds {
+		if err := removeGroupMember(ctx, client, groupId, userId); err != nil {
+			return nil, fmt.Errorf("error removing user from group %q: %w", groupId, err)
+		}
+		b.Logger().Info("removed user from group", "user_id", userId, "group_id", groupId)
+	}
+
+	if err := deleteToken(ctx, b.Logger(), client, accountId, userId, ephemeralRoleId, authTokenId); err != nil {
 		return nil, fmt.Errorf("error revoking account: %w", err)
 	}
 	return nil, nil
@@ -142,8 +227,12 @@ func (b *boundaryBackend) workerRevoke(ctx context.Context, req *logical.Request
 		}
 	}
 
-	if err := deleteWorker(ctx, client, workerId); err != nil {
-		return nil, fmt.Errorf("error revoking worker")
+	if err := deleteWorker(ctx, b.Logger(), client, workerId); err != nil {
+		return nil, fmt.Errorf("error revoking worker: %w", err)
+	}
+
+	if err := req.Storage.Delete(ctx, pendingWorkerStoragePrefix+workerId); err != nil {
+		return nil, fmt.Errorf("error removing pending worker: %w", err)
 	}
 	return nil, nil
 }
@@ -201,6 +290,17 @@ func (b *boundaryBackend) accountRenew(ctx context.Context, req *logical.Request
 }
 
 func (b *boundaryBackend) workerRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	// A worker deleted for not activating in time cannot come back
+	if workerId, ok := req.Secret.InternalData["worker_id"].(string); ok {
+		pending, err := getPendingWorker(ctx, req.Storage, workerId)
+		if err != nil {
+			return nil, err
+		}
+		if pending != nil && pending.Deleted {
+			return logical.ErrorResponse("worker %q was deleted as it did not activate within the role's activation_timeout", workerId), nil
+		}
+	}
+
 	ttlRaw, ok := req.Secret.InternalData["ttl"]
 	if !ok {
 		return nil, fmt.Errorf("secret is missing ttl internal data")
@@ -224,55 +324,76 @@ func (b *boundaryBackend) workerRenew(ctx context.Context, req *logical.Request,
 	return resp, nil
 }
 
-// createToken calls the Boundary client and creates a new Boundary account
-func createAccount(ctx context.Context, c *boundaryClient, role string, authMethodID string, boundaryRoles string, scopeId string) (*boundaryAccount, error) {
-
-	// Accounts client
-	aClient := accounts.NewClient(c.Client)
+// generatePassword creates a password from the named Vault password
+// policy, or with the default generator when no policy is given.
+func (b *boundaryBackend) generatePassword(ctx context.Context, policy string) (string, error) {
+	if policy == "" {
+		return password.Generate(16, 10, 0, false, false)
+	}
 
-	// Setting up the loginName using role_id + randomly generated string
-	loginNamePostfix, err := password.Generate(8, 0, 0, true, false)
+	pw, err := b.System().GeneratePasswordFromPolicy(ctx, policy)
 	if err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("unable to generate password from policy %q: %w", policy, err)
 	}
-	loginName := `vault-role-` + role + `-` + loginNamePostfix
+
+	return pw, nil
+}
+
+// createToken calls the Boundary client and creates a new Boundary account.
+// Each step is recorded in the WAL so that a failure part way through can be
+// rolled back by walRollback.
+func createAccount(ctx context.Context, s logical.Storage, c *boundaryClient, roleEntry *boundaryRoleEntry, loginName string, userName string, accountPassword string) (*boundaryAccount, error) {
+
+	// Accounts client
+	aClient := accounts.NewClient(c.Client)
 
 	var accountOpts []accounts.Option
 	accountOpts = append(accountOpts, accounts.WithPasswordAccountLoginName(loginName))
-	accountOpts = append(accountOpts, accounts.WithName(loginName))
+	accountOpts = append(accountOpts, accounts.WithName(userName))
+
+	accountOpts = append(accountOpts, accounts.WithPasswordAccountPassword(accountPassword))
 
-	// Generating a password
-	accountPassword, err := password.Generate(16, 10, 0, false, false)
+	var walIds []string
+
+	walId, err := framework.PutWAL(ctx, s, walAccountKind, &walAccount{
+		AuthMethodId: roleEntry.AuthMethodID,
+		LoginName:    loginName,
+	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("error writing WAL entry: %w", err)
 	}
-
-	accountOpts = append(accountOpts, accounts.WithPasswordAccountPassword(accountPassword))
+	walIds = append(walIds, walId)
 
 	// Creating an account
-	acr, err := aClient.Create(ctx, authMethodID, accountOpts...)
+	acr, err := aClient.Create(ctx, roleEntry.AuthMethodID, accountOpts...)
 	if err != nil {
-
-		return nil, err
+		return nil, fmt.Errorf("error creating account: %w", discardRejectedWAL(ctx, s, walId, err))
 	}
 
 	uclient := users.NewClient(c.Client)
 
 	var userOpts []users.Option
 
-	userOpts = append(userOpts, users.WithName(loginName))
+	userOpts = append(userOpts, users.WithName(userName))
 
-	ucr, err := uclient.Create(ctx, scopeId, userOpts...)
+	walId, err = framework.PutWAL(ctx, s, walUserKind, &walUser{
+		ScopeId: roleEntry.ScopeId,
+		Name:    userName,
+	})
 	if err != nil {
+		return nil, fmt.Errorf("error writing WAL entry: %w", err)
+	}
+	walIds = append(walIds, walId)
 
-		return nil, err
+	ucr, err := uclient.Create(ctx, roleEntry.ScopeId, userOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating user: %w", discardRejectedWAL(ctx, s, walId, err))
 	}
 	var accountList []string
 	accountList = append(accountList, acr.Item.Id)
 	_, err = uclient.AddAccounts(ctx, ucr.Item.Id, ucr.Item.Version, accountList)
 	if err != nil {
-
-		return nil, err
+		return nil, fmt.Errorf("error adding account to user: %w", err)
 	}
 
 	var principalIds []string
@@ -281,60 +402,284 @@ func createAccount(ctx context.Context, c *boundaryClient, role string, authMeth
 	rClient := roles.NewClient(c.Client)
 
 	var boundaryRoleIds []string
-	var rolesList []string
+	for _, roleId := range roleEntry.BoundaryRoles {
+
+		var opts []roles.Option
+		version, err := rClient.Read(ctx, roleId, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("error reading role %q: %w", roleId, err)
+		}
+
+		walId, err = framework.PutWAL(ctx, s, walRolePrincipalKind, &walRolePrincipal{
+			RoleId:      roleId,
+			PrincipalId: ucr.Item.Id,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error writing WAL entry: %w", err)
+		}
+		walIds = append(walIds, walId)
+
+		rcr, err := rClient.AddPrincipals(ctx, roleId, version.Item.Version, principalIds, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("error adding user to role %q: %w", roleId, err)
+		}
+
+		boundaryRoleIds = append(boundaryRoleIds, rcr.Item.Id)
+	}
+
+	gClient := groups.NewClient(c.Client)
+
+	var boundaryGroupIds []string
+
+	for _, groupId := range roleEntry.BoundaryGroups {
+		grr, err := gClient.Read(ctx, groupId)
+		if err != nil {
+			return nil, fmt.Errorf("error reading group %q: %w", groupId, err)
+		}
 
-	rolesList = strings.Split(boundaryRoles, ",")
+		walId, err = framework.PutWAL(ctx, s, walGroupMemberKind, &walGroupMember{
+			GroupId:  groupId,
+			MemberId: ucr.Item.Id,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error writing WAL entry: %w", err)
+		}
+		walIds = append(walIds, walId)
 
-	var boundaryRoleIdsString string
+		// Passing the version read above makes Boundary reject the
+		// change if the group was modified in the meantime
+		gur, err := gClient.AddMembers(ctx, groupId, grr.Item.Version, principalIds)
+		if err != nil {
+			return nil, fmt.Errorf("error adding user to group %q: %w", groupId, err)
+		}
 
-	for _, s := range rolesList {
+		boundaryGroupIds = append(boundaryGroupIds, gur.Item.Id)
+	}
 
+	// Create a role just for this user when the Vault role carries its own grants
+	var ephemeralRoleId string
+	if len(roleEntry.GrantStrings) > 0 {
 		var opts []roles.Option
-		version, err := rClient.Read(ctx, s, opts...)
+		opts = append(opts, roles.WithName(userName))
+		opts = append(opts, roles.WithDescription("Generated by Vault for "+loginName))
+		if roleEntry.GrantScopeId != "" {
+			opts = append(opts, roles.WithGrantScopeId(roleEntry.GrantScopeId))
+		}
+
+		walId, err = framework.PutWAL(ctx, s, walRoleKind, &walRole{
+			ScopeId: roleEntry.ScopeId,
+			Name:    userName,
+		})
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("error writing WAL entry: %w", err)
 		}
+		walIds = append(walIds, walId)
 
-		rcr, err := rClient.AddPrincipals(ctx, s, version.Item.Version, principalIds, opts...)
+		rcr, err := rClient.Create(ctx, roleEntry.ScopeId, opts...)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("error creating role: %w", discardRejectedWAL(ctx, s, walId, err))
 		}
 
-		boundaryRoleIds = append(boundaryRoleIds, rcr.Item.Id)
+		rgr, err := rClient.AddGrants(ctx, rcr.Item.Id, rcr.Item.Version, roleEntry.GrantStrings)
+		if err != nil {
+			return nil, fmt.Errorf("error adding grants to role: %w", err)
+		}
+
+		_, err = rClient.AddPrincipals(ctx, rcr.Item.Id, rgr.Item.Version, principalIds)
+		if err != nil {
+			return nil, fmt.Errorf("error adding user to role: %w", err)
+		}
+
+		ephemeralRoleId = rcr.Item.Id
 	}
-	boundaryRoleIdsString = strings.Join(boundaryRoleIds, ",")
 
-	return &boundaryAccount{
-		AccountId:     acr.Item.Id,
-		LoginName:     acr.Item.Name,
-		Password:      accountPassword,
-		AuthMethodId:  acr.Item.AuthMethodId,
-		BoundaryRoles: boundaryRoleIdsString,
-		UserId:        ucr.Item.Id,
-	}, nil
+	// Log in as the new account so the caller does not have to. Deleting
+	// the account on rollback also removes the token, so it needs no WAL.
+	var token *authtokens.AuthToken
+	if roleEntry.CredentialType == credentialTypeAuthToken {
+		token, err = authenticateAccount(ctx, c, acr.Item.AuthMethodId, loginName, accountPassword)
+		if err != nil {
+			return nil, fmt.Errorf("error authenticating as account: %w", err)
+		}
+	}
+
+	// Everything was created, so commit the WAL entries
+	for _, id := range walIds {
+		if err := framework.DeleteWAL(ctx, s, id); err != nil {
+			return nil, fmt.Errorf("error committing WAL entry: %w", err)
+		}
+	}
+
+	account := &boundaryAccount{
+		AccountId:       acr.Item.Id,
+		LoginName:       loginName,
+		Password:        accountPassword,
+		AuthMethodId:    acr.Item.AuthMethodId,
+		BoundaryRoles:   boundaryRoleIds,
+		BoundaryGroups:  boundaryGroupIds,
+		UserId:          ucr.Item.Id,
+		EphemeralRoleId: ephemeralRoleId,
+	}
+
+	if token != nil {
+		account.AuthTokenId = token.Id
+		account.AuthToken = token.Token
+		account.AuthTokenExpiration = token.ExpirationTime
+	}
+
+	return account, nil
 }
 
-// deleteToken calls the boundary client to remove account
-func deleteToken(ctx context.Context, c *boundaryClient, accountId string, userId string) error {
-	ucr := users.NewClient(c.Client)
-	var userOpts []users.Option
-	_, err := ucr.Delete(ctx, userId, userOpts...)
+// authenticateAccount logs in to a password auth method and
+// returns the auth token Boundary issues.
+func authenticateAccount(ctx context.Context, c *boundaryClient, authMethodId string, loginName string, password string) (*authtokens.AuthToken, error) {
+	credentials := map[string]interface{}{
+		"login_name": loginName,
+		"password":   password,
+	}
+
+	amClient := authmethods.NewClient(c.authClient)
+
+	authenticationResult, err := amClient.Authenticate(ctx, authMethodId, "login", credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	var token authtokens.AuthToken
+	if err := json.Unmarshal(authenticationResult.GetRawAttributes(), &token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// deleteToken calls the boundary client to remove account, along with
+// the role generated for it and its auth token if there are any.
+// Resources that have already been deleted are skipped, so that a
+// revocation which failed part way through can be retried.
+func deleteToken(ctx context.Context, logger hclog.Logger, c *boundaryClient, accountId string, userId string, ephemeralRoleId string, authTokenId string) error {
+	err := deleteIfExists(logger, "auth token", authTokenId, func() error {
+		_, err := authtokens.NewClient(c.Client).Delete(ctx, authTokenId)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	acr := accounts.NewClient(c.Client)
+	err = deleteIfExists(logger, "role", ephemeralRoleId, func() error {
+		_, err := roles.NewClient(c.Client).Delete(ctx, ephemeralRoleId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
 
-	var opts []accounts.Option
-	_, err = acr.Delete(ctx, accountId, opts...)
+	err = deleteIfExists(logger, "user", userId, func() error {
+		_, err := users.NewClient(c.Client).Delete(ctx, userId)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
+	return deleteIfExists(logger, "account", accountId, func() error {
+		_, err := accounts.NewClient(c.Client).Delete(ctx, accountId)
+		return err
+	})
+}
+
+// deleteIfExists calls del to delete a Boundary resource, treating a
+// 404 as the resource having been deleted already. Nothing is done
+// when id is empty.
+func deleteIfExists(logger hclog.Logger, resource string, id string, del func() error) error {
+	if id == "" {
+		return nil
+	}
+
+	err := del()
+	switch {
+	case err == nil:
+		logger.Info("deleted "+resource, "id", id)
+	case isNotFound(err):
+		logger.Warn(resource+" was already deleted", "id", id)
+	default:
+		return fmt.Errorf("error deleting %s %q: %w", resource, id, err)
+	}
+
 	return nil
 }
 
-func createWorker(ctx context.Context, c *boundaryClient, scopeId string, workerName string, description string) (*boundaryWorker, error) {
+// filterEscaper escapes the only characters that are special in a
+// double quoted string in a Boundary list filter.
+var filterEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// filterEquals returns a Boundary list filter that matches items
+// whose value at pointer is value.
+func filterEquals(pointer string, value string) string {
+	return `"` + pointer + `" == "` + filterEscaper.Replace(value) + `"`
+}
+
+// removeGroupMember removes the user from the group, if it is
+// still a member and the group still exists.
+func removeGroupMember(ctx context.Context, c *boundaryClient, groupId string, userId string) error {
+	gcr := groups.NewClient(c.Client)
+
+	grr, err := gcr.Read(ctx, groupId)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, id := range grr.Item.MemberIds {
+		if id == userId {
+			_, err := gcr.RemoveMembers(ctx, groupId, grr.Item.Version, []string{userId})
+			if isNotFound(err) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cancelUserSessions cancels every active or pending session
+// belonging to the user, in any scope, and returns how many
+// sessions were cancelled.
+func cancelUserSessions(ctx context.Context, c *boundaryClient, userId string) (int, error) {
+	if userId == "" {
+		return 0, nil
+	}
+
+	scl := sessions.NewClient(c.Client)
+
+	filter := filterEquals("/item/user_id", userId) + ` and ("/item/status" == "active" or "/item/status" == "pending")`
+	slr, err := scl.List(ctx, "global", sessions.WithRecursive(true), sessions.WithFilter(filter))
+	if err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	for _, session := range slr.Items {
+		_, err := scl.Cancel(ctx, session.Id, session.Version)
+		if isNotFound(err) {
+			// The session ended and was removed since it was listed
+			continue
+		}
+		if err != nil {
+			return cancelled, fmt.Errorf("error cancelling session %q: %w", session.Id, err)
+		}
+		cancelled++
+	}
+
+	return cancelled, nil
+}
+
+// createWorker creates a controller-led worker and adds any tags to
+// it. The worker is deleted again if the tags cannot be added.
+func createWorker(ctx context.Context, c *boundaryClient, scopeId string, workerName string, description string, tags map[string][]string) (*boundaryWorker, error) {
 	wcl := workers.NewClient(c.Client)
 	var workerOpts []workers.Option
 	workerOpts = append(workerOpts, workers.WithAutomaticVersioning(true))
@@ -342,25 +687,89 @@ func createWorker(ctx context.Context, c *boundaryClient, scopeId string, worker
 	workerOpts = append(workerOpts, workers.WithName(workerName))
 	wcr, err := wcl.CreateControllerLed(ctx, scopeId, workerOpts...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error creating worker: %w", err)
 	}
 
-	return &boundaryWorker{
+	worker := &boundaryWorker{
 		WorkerId:        wcr.Item.Id,
 		ActivationToken: wcr.Item.ControllerGeneratedActivationToken,
 		WorkerName:      wcr.Item.Name,
 		Description:     wcr.Item.Description,
-	}, nil
+	}
+
+	if err := addWorkerTags(ctx, wcl, worker, wcr.Item.Version, tags); err != nil {
+		return nil, err
+	}
+
+	return worker, nil
 }
 
-func deleteWorker(ctx context.Context, c *boundaryClient, workerId string) error {
+// createWorkerLed registers a worker from the auth token the worker
+// generated itself, and adds any tags to it. The worker is deleted
+// again if the tags cannot be added.
+func createWorkerLed(ctx context.Context, c *boundaryClient, scopeId string, authToken string, workerName string, description string, tags map[string][]string) (*boundaryWorker, error) {
 	wcl := workers.NewClient(c.Client)
 	var workerOpts []workers.Option
+	workerOpts = append(workerOpts, workers.WithDescription(description))
+	workerOpts = append(workerOpts, workers.WithName(workerName))
+	wcr, err := wcl.CreateWorkerLed(ctx, authToken, scopeId, workerOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("error registering worker: %w", err)
+	}
+
+	worker := &boundaryWorker{
+		WorkerId:    wcr.Item.Id,
+		WorkerName:  wcr.Item.Name,
+		Description: wcr.Item.Description,
+	}
+
+	if err := addWorkerTags(ctx, wcl, worker, wcr.Item.Version, tags); err != nil {
+		return nil, err
+	}
+
+	return worker, nil
+}
 
-	_, err := wcl.Delete(ctx, workerId, workerOpts...)
+// workerNameInUse reports whether a worker with the name already
+// exists in the scope.
+func workerNameInUse(ctx context.Context, c *boundaryClient, scopeId string, name string) (bool, error) {
+	wcl := workers.NewClient(c.Client)
+
+	filter := filterEquals("/item/name", name)
+	wlr, err := wcl.List(ctx, scopeId, workers.WithFilter(filter))
 	if err != nil {
-		return err
+		return false, err
 	}
 
+	return len(wlr.Items) > 0, nil
+}
+
+// addWorkerTags adds tags to a worker that has just been created,
+// deleting the worker if they cannot be added. Boundary only lets
+// tags be set through the API once the worker exists. They are
+// stored as the worker's API tags, as config tags can only come
+// from the worker's own config file.
+func addWorkerTags(ctx context.Context, wcl *workers.Client, worker *boundaryWorker, version uint32, tags map[string][]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	wur, err := wcl.AddWorkerTags(ctx, worker.WorkerId, version, tags)
+	if err != nil {
+		if _, derr := wcl.Delete(ctx, worker.WorkerId); derr != nil {
+			return fmt.Errorf("error adding tags to worker: %v; error deleting worker: %w", err, derr)
+		}
+		return fmt.Errorf("error adding tags to worker: %w", err)
+	}
+
+	worker.WorkerTags = wur.Item.ApiTags
 	return nil
 }
+
+// deleteWorker deletes the worker, if it still exists.
+func deleteWorker(ctx context.Context, logger hclog.Logger, c *boundaryClient, workerId string) error {
+	return deleteIfExists(logger, "worker", workerId, func() error {
+		_, err := workers.NewClient(c.Client).Delete(ctx, workerId)
+		return err
+	})
+}
@@ -0,0 +1,79 @@
+package boundarysecrets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelUserSessions(t *testing.T) {
+	fake := &fakeBoundary{
+		expiration: time.Now().Add(time.Hour),
+		sessions:   []string{"s_1234567890", "s_0987654321"},
+	}
+	c := newTestClient(t, fake)
+
+	cancelled, err := cancelUserSessions(context.Background(), c, "u_1234567890")
+	require.NoError(t, err)
+	require.Equal(t, 2, cancelled)
+	require.Equal(t, []string{"s_1234567890", "s_0987654321"}, fake.cancelled)
+	require.Contains(t, fake.sessionFilter, `"/item/user_id" == "u_1234567890"`)
+}
+
+func TestRemoveGroupMember(t *testing.T) {
+	fake := &fakeBoundary{
+		expiration: time.Now().Add(time.Hour),
+		groups:     map[string][]string{"g_1234567890": {"u_1234567890", "u_0987654321"}},
+	}
+	c := newTestClient(t, fake)
+
+	require.NoError(t, removeGroupMember(context.Background(), c, "g_1234567890", "u_1234567890"))
+	require.Equal(t, []string{"u_0987654321"}, fake.groups["g_1234567890"])
+
+	// Removing a user that has already left is not an error
+	require.NoError(t, removeGroupMember(context.Background(), c, "g_1234567890", "u_1234567890"))
+
+	// Nor is removing a user from a group that has been deleted
+	require.NoError(t, removeGroupMember(context.Background(), c, "g_0987654321", "u_1234567890"))
+}
+
+func TestDeleteToken(t *testing.T) {
+	t.Run("Deletes Everything", func(t *testing.T) {
+		fake := &fakeBoundary{expiration: time.Now().Add(time.Hour)}
+		c := newTestClient(t, fake)
+
+		err := deleteToken(context.Background(), hclog.NewNullLogger(), c, "acctpw_1234567890", "u_1234567890", "r_1234567890", "at_1234567890")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"/v1/auth-tokens/at_1234567890",
+			"/v1/roles/r_1234567890",
+			"/v1/users/u_1234567890",
+			"/v1/accounts/acctpw_1234567890",
+		}, fake.deleted)
+	})
+
+	t.Run("Already Deleted User", func(t *testing.T) {
+		fake := &fakeBoundary{
+			expiration: time.Now().Add(time.Hour),
+			missing:    []string{"/v1/users/u_1234567890"},
+		}
+		c := newTestClient(t, fake)
+
+		err := deleteToken(context.Background(), hclog.NewNullLogger(), c, "acctpw_1234567890", "u_1234567890", "", "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"/v1/accounts/acctpw_1234567890"}, fake.deleted)
+	})
+}
+
+func TestFilterEquals(t *testing.T) {
+	t.Run("Plain", func(t *testing.T) {
+		require.Equal(t, `"/item/name" == "edge-1"`, filterEquals("/item/name", "edge-1"))
+	})
+
+	t.Run("Escaped", func(t *testing.T) {
+		require.Equal(t, `"/item/name" == "say \"hi\" \\ é"`, filterEquals("/item/name", `say "hi" \ é`))
+	})
+}
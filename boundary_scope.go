@@ -0,0 +1,336 @@
+package boundarysecrets
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/boundary/api/authmethods"
+	"github.com/hashicorp/boundary/api/roles"
+	"github.com/hashicorp/boundary/api/scopes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/sethvargo/go-password/password"
+)
+
+const defaultScopeNameTemplate = `vault-{{.RoleName}}-{{random 8 | lowercase}}`
+
+// defaultScopeAdminGrants are granted to a scope's bootstrap admin
+// when the role does not have grant strings of its own.
+var defaultScopeAdminGrants = []string{"id=*;type=*;actions=*"}
+
+type boundaryScope struct {
+	ScopeId       string `json:"scope_id"`
+	Name          string `json:"scope_name"`
+	Type          string `json:"scope_type"`
+	ParentScopeId string `json:"parent_scope_id"`
+
+	// Admin is the bootstrap admin created for the scope, if any,
+	// and AdminRoleId the role in the scope that it is granted.
+	Admin       *boundaryAccount `json:"admin"`
+	AdminRoleId string           `json:"admin_role_id"`
+}
+
+func (b *boundaryBackend) boundaryScope() *framework.Secret {
+	return &framework.Secret{
+		Type:   Scope,
+		Revoke: b.scopeRevoke,
+		Renew:  b.accountRenew,
+		Fields: map[string]*framework.FieldSchema{
+			"scope_id": {
+				Type:        framework.TypeString,
+				Description: "ID of the Boundary scope",
+			},
+			"scope_name": {
+				Type:        framework.TypeString,
+				Description: "Name of the Boundary scope",
+			},
+			"scope_type": {
+				Type:        framework.TypeString,
+				Description: "Type of the Boundary scope, either `org` or `project`",
+			},
+			"parent_scope_id": {
+				Type:        framework.TypeString,
+				Description: "ID of the scope the Boundary scope was created in",
+			},
+			"admin_role_id": {
+				Type:        framework.TypeString,
+				Description: "ID of the Boundary role granting the bootstrap admin access to the scope",
+			},
+			"login_name": {
+				Type:        framework.TypeString,
+				Description: "Login name of the bootstrap admin",
+			},
+			"password": {
+				Type:        framework.TypeString,
+				Description: "Password of the bootstrap admin",
+			},
+			"auth_method_id": {
+				Type:        framework.TypeString,
+				Description: "Auth method ID of the bootstrap admin's account",
+			},
+			"account_id": {
+				Type:        framework.TypeString,
+				Description: "Boundary Account ID of the bootstrap admin",
+			},
+			"user_id": {
+				Type:        framework.TypeString,
+				Description: "Boundary User ID of the bootstrap admin",
+			},
+			"auth_token_id": {
+				Type:        framework.TypeString,
+				Description: "ID of the Boundary auth token issued to the bootstrap admin",
+			},
+			"auth_token": {
+				Type:        framework.TypeString,
+				Description: "Boundary auth token issued to the bootstrap admin",
+			},
+			"auth_token_expiration": {
+				Type:        framework.TypeString,
+				Description: "Expiration time of the Boundary auth token",
+			},
+		},
+	}
+}
+
+// scopeRevoke deletes the scope, which deletes everything within it,
+// and then the bootstrap admin if there is one.
+func (b *boundaryBackend) scopeRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %w", err)
+	}
+
+	scopeId := ""
+	scopeIdRaw, ok := req.Secret.InternalData["scope_id"]
+	if ok {
+		scopeId, ok = scopeIdRaw.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for scope_id in secret internal data")
+		}
+	}
+
+	userId := ""
+	userIdRaw, ok := req.Secret.InternalData["user_id"]
+	if ok {
+		userId, ok = userIdRaw.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for user_id in secret internal data")
+		}
+	}
+
+	accountId := ""
+	accountIdRaw, ok := req.Secret.InternalData["account_id"]
+	if ok {
+		accountId, ok = accountIdRaw.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for account_id in secret internal data")
+		}
+	}
+
+	authTokenId := ""
+	authTokenIdRaw, ok := req.Secret.InternalData["auth_token_id"]
+	if ok {
+		authTokenId, ok = authTokenIdRaw.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for auth_token_id in secret internal data")
+		}
+	}
+
+	err = deleteIfExists(b.Logger(), "scope", scopeId, func() error {
+		_, err := scopes.NewClient(client.Client).Delete(ctx, scopeId)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error revoking scope: %w", err)
+	}
+
+	if err := deleteToken(ctx, b.Logger(), client, accountId, userId, "", authTokenId); err != nil {
+		return nil, fmt.Errorf("error revoking scope admin: %w", err)
+	}
+
+	return nil, nil
+}
+
+// createScope creates a child scope of the role's scope, along with
+// a bootstrap admin if the role asks for one. The scope is deleted
+// again if the admin cannot be created.
+func (b *boundaryBackend) createScope(ctx context.Context, req *logical.Request, roleEntry *boundaryRoleEntry) (*boundaryScope, error) {
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	templateData, err := b.newNameTemplateData(req, roleEntry.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	nameTemplate := roleEntry.ScopeNameTemplate
+	if nameTemplate == "" {
+		nameTemplate = defaultScopeNameTemplate
+	}
+
+	name, err := renderNameTemplate(nameTemplate, templateData)
+	if err != nil {
+		return nil, newUserError(fmt.Errorf("error generating scope name: %w", err))
+	}
+
+	// Scope names need not be unique across leases, so the
+	// description carries a random ID that rollback matches on
+	requestId, err := password.Generate(16, 6, 0, true, true)
+	if err != nil {
+		return nil, err
+	}
+	description := fmt.Sprintf("Generated by Vault for role %s (%s)", roleEntry.Name, requestId)
+
+	walId, err := framework.PutWAL(ctx, req.Storage, walScopeKind, &walScope{
+		ScopeId:     roleEntry.ScopeId,
+		Name:        name,
+		Description: description,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error writing WAL entry: %w", err)
+	}
+
+	// Boundary creates an org under global and a project under an org
+	scl := scopes.NewClient(client.Client)
+	scr, err := scl.Create(ctx, roleEntry.ScopeId,
+		scopes.WithName(name),
+		scopes.WithDescription(description),
+	)
+	if err != nil {
+		return nil, classifyError(fmt.Errorf("error creating scope: %w", discardRejectedWAL(ctx, req.Storage, walId, err)))
+	}
+
+	scope := &boundaryScope{
+		ScopeId:       scr.Item.Id,
+		Name:          scr.Item.Name,
+		Type:          scr.Item.Type,
+		ParentScopeId: roleEntry.ScopeId,
+	}
+
+	if roleEntry.BootstrapAdmin {
+		scope.Admin, scope.AdminRoleId, err = b.createScopeAdmin(ctx, req, client, roleEntry, scope.ScopeId)
+		if err != nil {
+			// Leave the WAL entry for the rollback handler if the
+			// scope cannot be deleted now
+			if _, derr := scl.Delete(ctx, scope.ScopeId); derr != nil {
+				return nil, fmt.Errorf("%w; error deleting scope: %v", err, derr)
+			}
+			if derr := framework.DeleteWAL(ctx, req.Storage, walId); derr != nil {
+				return nil, fmt.Errorf("%w; error removing WAL entry: %v", err, derr)
+			}
+			return nil, err
+		}
+	}
+
+	if err := framework.DeleteWAL(ctx, req.Storage, walId); err != nil {
+		return nil, fmt.Errorf("error committing WAL entry: %w", err)
+	}
+
+	return scope, nil
+}
+
+// createScopeAdmin creates a user with an account in the role's auth
+// method, and a role in the new scope that grants it the role's grant
+// strings, or full access if there are none. The user lives in the
+// auth method's scope, as a new scope has no auth methods of its own.
+func (b *boundaryBackend) createScopeAdmin(ctx context.Context, req *logical.Request, client *boundaryClient, roleEntry *boundaryRoleEntry, scopeId string) (*boundaryAccount, string, error) {
+	amr, err := authmethods.NewClient(client.Client).Read(ctx, roleEntry.AuthMethodID)
+	if err != nil {
+		return nil, "", classifyError(fmt.Errorf("error reading auth method %q: %w", roleEntry.AuthMethodID, err))
+	}
+
+	admin := *roleEntry
+	admin.ScopeId = amr.Item.ScopeId
+	admin.BoundaryRoles = nil
+	admin.BoundaryGroups = nil
+	admin.GrantStrings = nil
+
+	account, err := b.createAccount(ctx, req, &admin)
+	if err != nil {
+		return nil, "", err
+	}
+
+	grants := roleEntry.GrantStrings
+	if len(grants) == 0 {
+		grants = defaultScopeAdminGrants
+	}
+
+	roleId, err := createScopeAdminRole(ctx, client, scopeId, account, grants)
+	if err != nil {
+		if derr := deleteToken(ctx, b.Logger(), client, account.AccountId, account.UserId, "", account.AuthTokenId); derr != nil {
+			return nil, "", fmt.Errorf("%w; error deleting scope admin: %v", err, derr)
+		}
+		return nil, "", classifyError(err)
+	}
+
+	return account, roleId, nil
+}
+
+// createScopeAdminRole creates a role in the scope with the grants,
+// and the account's user as its only principal. The role needs no
+// cleanup of its own, as deleting the scope deletes it.
+func createScopeAdminRole(ctx context.Context, c *boundaryClient, scopeId string, account *boundaryAccount, grants []string) (string, error) {
+	rClient := roles.NewClient(c.Client)
+
+	rcr, err := rClient.Create(ctx, scopeId,
+		roles.WithName(account.LoginName),
+		roles.WithDescription("Generated by Vault for "+account.LoginName),
+	)
+	if err != nil {
+		return "", fmt.Errorf("error creating admin role: %w", err)
+	}
+
+	rgr, err := rClient.AddGrants(ctx, rcr.Item.Id, rcr.Item.Version, grants)
+	if err != nil {
+		return "", fmt.Errorf("error adding grants to admin role: %w", err)
+	}
+
+	_, err = rClient.AddPrincipals(ctx, rcr.Item.Id, rgr.Item.Version, []string{account.UserId})
+	if err != nil {
+		return "", fmt.Errorf("error adding user to admin role: %w", err)
+	}
+
+	return rcr.Item.Id, nil
+}
+
+// scopeResponse returns a scope lease for a scope created from the
+// role.
+func (b *boundaryBackend) scopeResponse(role *boundaryRoleEntry, scope *boundaryScope) *logical.Response {
+	data := map[string]interface{}{
+		"scope_id":        scope.ScopeId,
+		"scope_name":      scope.Name,
+		"scope_type":      scope.Type,
+		"parent_scope_id": scope.ParentScopeId,
+	}
+
+	internal := map[string]interface{}{
+		"scope_id": scope.ScopeId,
+		"ttl":      role.TTL.Seconds(),
+		"max_ttl":  role.MaxTTL.Seconds(),
+	}
+
+	if admin := scope.Admin; admin != nil {
+		data["admin_role_id"] = scope.AdminRoleId
+		data["account_id"] = admin.AccountId
+		data["user_id"] = admin.UserId
+		data["auth_method_id"] = admin.AuthMethodId
+		data["login_name"] = admin.LoginName
+
+		if role.CredentialType == credentialTypeAuthToken {
+			data["auth_token_id"] = admin.AuthTokenId
+			data["auth_token"] = admin.AuthToken
+			data["auth_token_expiration"] = admin.AuthTokenExpiration.Format(time.RFC3339)
+		} else {
+			data["password"] = admin.Password
+		}
+
+		internal["account_id"] = admin.AccountId
+		internal["user_id"] = admin.UserId
+		internal["auth_token_id"] = admin.AuthTokenId
+	}
+
+	return b.Secret(Scope).Response(data, internal)
+}
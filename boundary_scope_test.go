@@ -0,0 +1,94 @@
+package boundarysecrets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+const scopeRoleName = "testboundaryscope"
+
+// TestScopeRole checks that a scope role creates a scope with a
+// bootstrap admin, and that revoking the lease deletes both.
+func TestScopeRole(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	fake := &fakeBoundary{expiration: time.Now().Add(time.Hour)}
+	configureTestBackend(t, b, s, fake)
+
+	t.Run("Bootstrap Admin Needs Auth Method", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, scopeRoleName, map[string]interface{}{
+			"scope_id":        "global",
+			"role_type":       "scope",
+			"bootstrap_admin": true,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	_, err := testTokenRoleCreate(t, b, s, scopeRoleName, map[string]interface{}{
+		"scope_id":            "global",
+		"role_type":           "scope",
+		"ttl":                 testTTL,
+		"scope_name_template": `preview-{{.RoleName}}-{{random 4 | lowercase}}`,
+		"bootstrap_admin":     true,
+		"auth_method_id":      auth_method_id,
+	})
+	require.NoError(t, err)
+
+	var secret *logical.Secret
+
+	t.Run("Create Scope", func(t *testing.T) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + scopeRoleName,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+
+		require.Equal(t, "o_1234567890", resp.Data["scope_id"])
+		require.Equal(t, "org", resp.Data["scope_type"])
+		require.Equal(t, "global", resp.Data["parent_scope_id"])
+		require.Regexp(t, `^preview-`+scopeRoleName+`-[a-z0-9]{4}$`, resp.Data["scope_name"])
+		require.Equal(t, []string{resp.Data["scope_name"].(string)}, fake.scopeNames)
+
+		require.Equal(t, "r_0987654321", resp.Data["admin_role_id"])
+		require.Equal(t, "acctpw_0987654321", resp.Data["account_id"])
+		require.NotEmpty(t, resp.Data["password"])
+		require.Equal(t, time.Duration(testTTL)*time.Second, resp.Secret.TTL)
+
+		secret = resp.Secret
+	})
+
+	t.Run("Renew", func(t *testing.T) {
+		require.NotNil(t, secret)
+
+		renew, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RenewOperation,
+			Secret:    secret,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, time.Duration(testTTL)*time.Second, renew.Secret.TTL)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		require.NotNil(t, secret)
+
+		_, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RevokeOperation,
+			Secret:    secret,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"/v1/scopes/o_1234567890",
+			"/v1/users/u_1234567890",
+			"/v1/accounts/acctpw_0987654321",
+		}, fake.deleted)
+	})
+}
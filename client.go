@@ -1,15 +1,48 @@
 package boundarysecrets
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
 	boundary "github.com/hashicorp/boundary/api"
+	"github.com/hashicorp/boundary/api/accounts"
 	"github.com/hashicorp/boundary/api/authmethods"
+	"github.com/hashicorp/boundary/api/authtokens"
+	"github.com/hashicorp/boundary/api/users"
+	"github.com/hashicorp/go-rootcerts"
+)
+
+const (
+	// tokenRefreshWindow is how long before the auth token expires
+	// that the client re-authenticates ahead of the next request.
+	tokenRefreshWindow = 5 * time.Minute
+
+	// tokenIdleTimeout approximates Boundary's default auth token
+	// time to stale, which is not returned on authentication.
+	tokenIdleTimeout = 24 * time.Hour
 )
 
 type boundaryClient struct {
 	*boundary.Client
+
+	config *boundaryConfig
+
+	// authClient is an unauthenticated client sharing the
+	// underlying transport, used only to log in.
+	authClient *boundary.Client
+
+	lock       sync.Mutex
+	expiration time.Time
+	lastUsed   time.Time
 }
 
 func newClient(config *boundaryConfig) (*boundaryClient, error) {
@@ -18,44 +51,336 @@ func newClient(config *boundaryConfig) (*boundaryClient, error) {
 		return nil, errors.New("client configuration was nil")
 	}
 
-	if config.LoginName == "" {
-		return nil, errors.New("login name was not defined")
-	}
+	if config.AuthToken == "" && config.RecoveryKmsHcl == "" {
+		if config.LoginName == "" {
+			return nil, errors.New("login name was not defined")
+		}
 
-	if config.Password == "" {
-		return nil, errors.New("password was not defined")
+		if config.Password == "" {
+			return nil, errors.New("password was not defined")
+		}
+
+		if config.AuthMethodId == "" {
+			return nil, errors.New("auth-method ID was not defined")
+		}
 	}
 
 	if config.Addr == "" {
 		return nil, errors.New("boundary address was not defined")
 	}
 
-	if config.AuthMethodId == "" {
-		return nil, errors.New("auth-method ID was not defined")
+	authCfg := boundary.Config{
+		Addr: config.Addr,
+	}
+
+	authClient, err := boundary.NewClient(&authCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport, ok := authCfg.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("unexpected transport type for Boundary client")
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	c := &boundaryClient{
+		config:     config,
+		authClient: authClient,
 	}
 
 	cfg := boundary.Config{
 		Addr: config.Addr,
+		HttpClient: &http.Client{
+			Transport: &reauthTransport{
+				base:   transport,
+				client: c,
+			},
+		},
 	}
 
-	client, err := boundary.NewClient(&cfg)
+	// With a recovery KMS, Boundary's client signs a fresh recovery
+	// token for every request, so there is nothing to log in with.
+	if config.RecoveryKmsHcl != "" {
+		cfg.RecoveryKmsWrapper, err = recoveryKmsWrapper(context.Background(), config.RecoveryKmsHcl)
+		if err != nil {
+			return nil, err
+		}
+		cfg.HttpClient.Transport = transport
+	}
+
+	c.Client, err = boundary.NewClient(&cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	credentials := map[string]interface{}{
-		"login_name": config.LoginName,
-		"password":   config.Password,
+	if config.AuthToken != "" {
+		err = c.useAuthToken(context.Background())
+	} else if config.RecoveryKmsHcl == "" {
+		err = c.authenticate(context.Background())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// verifyConnection logs in with config and checks that the
+// resulting principal can list the users and accounts Vault
+// will manage.
+func verifyConnection(ctx context.Context, config *boundaryConfig) (*boundaryClient, error) {
+	client, err := newClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to authenticate: %w", err)
+	}
+
+	scopeId := "global"
+
+	if config.AuthMethodId != "" {
+		amr, err := authmethods.NewClient(client.Client).Read(ctx, config.AuthMethodId)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read auth method %q: %w", config.AuthMethodId, err)
+		}
+		scopeId = amr.Item.ScopeId
+
+		if _, err := accounts.NewClient(client.Client).List(ctx, config.AuthMethodId); err != nil {
+			return nil, fmt.Errorf("unable to list accounts in auth method %q: %w", config.AuthMethodId, err)
+		}
+	}
+
+	if _, err := users.NewClient(client.Client).List(ctx, scopeId); err != nil {
+		return nil, fmt.Errorf("unable to list users in scope %q: %w", scopeId, err)
+	}
+
+	return client, nil
+}
+
+// authTokenId extracts the public ID from a Boundary auth token,
+// which has the form at_<id>_<secret>.
+func authTokenId(token string) (string, error) {
+	parts := strings.SplitN(token, "_", 3)
+	if len(parts) != 3 || parts[0] != "at" || parts[1] == "" || parts[2] == "" {
+		return "", errors.New("auth token must have the form at_<id>_<secret>")
+	}
+
+	return parts[0] + "_" + parts[1], nil
+}
+
+// useAuthToken configures the client with a pre-issued auth token
+// and reads the token back to confirm it is valid and learn when
+// it expires.
+func (c *boundaryClient) useAuthToken(ctx context.Context) error {
+	id, err := authTokenId(c.config.AuthToken)
+	if err != nil {
+		return err
 	}
 
-	amClient := authmethods.NewClient(client)
+	c.Client.SetToken(c.config.AuthToken)
 
-	authenticationResult, err := amClient.Authenticate(context.Background(), config.AuthMethodId, "login", credentials)
+	atr, err := authtokens.NewClient(c.Client).Read(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error reading auth token: %w", err)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.expiration = atr.Item.ExpirationTime
+	c.lastUsed = time.Now()
+
+	return nil
+}
+
+// tokenExpiration returns when the client's current auth token expires.
+func (c *boundaryClient) tokenExpiration() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.expiration
+}
+
+// tlsConfig builds the TLS settings used to connect to the
+// Boundary controller, validating any PEM data in the config.
+func (c *boundaryConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.TLSSkipVerify,
+	}
+
+	if c.CACert != "" || c.CAPath != "" {
+		err := rootcerts.ConfigureTLS(tlsConfig, &rootcerts.Config{
+			CACertificate: []byte(c.CACert),
+			CAPath:        c.CAPath,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error loading CA certificates: %w", err)
+		}
+	}
+
+	if c.ClientCert != "" || c.ClientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(c.ClientCert), []byte(c.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// authenticate logs in with the configured credentials and
+// replaces the token used by the client. The caller must not
+// hold c.lock.
+func (c *boundaryClient) authenticate(ctx context.Context) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.authenticateLocked(ctx)
+}
+
+func (c *boundaryClient) authenticateLocked(ctx context.Context) error {
+	if c.config.AuthToken != "" {
+		return errors.New("cannot re-authenticate when using a pre-issued auth token")
+	}
+
+	token, err := authenticateAccount(ctx, c, c.config.AuthMethodId, c.config.LoginName, c.config.Password)
+	if err != nil {
+		return err
+	}
+
+	c.Client.SetToken(token.Token)
+	c.expiration = token.ExpirationTime
+	c.lastUsed = time.Now()
+
+	return nil
+}
+
+// tokenNeedsRefresh reports whether the current token has expired,
+// is about to, or has probably gone stale from lack of use.
+func (c *boundaryClient) tokenNeedsRefresh() bool {
+	if c.config.AuthToken != "" {
+		return false
+	}
+
+	now := time.Now()
+
+	if !c.expiration.IsZero() && now.Add(tokenRefreshWindow).After(c.expiration) {
+		return true
+	}
+
+	return now.Add(tokenRefreshWindow).After(c.lastUsed.Add(tokenIdleTimeout))
+}
+
+// refreshToken re-authenticates if the token is about to expire and
+// returns the token that should be used for the next request.
+func (c *boundaryClient) refreshToken(ctx context.Context) (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.tokenNeedsRefresh() {
+		if err := c.authenticateLocked(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	return c.Client.Token(), nil
+}
+
+// renewRejectedToken re-authenticates after Boundary rejects the
+// token `rejected`, unless another request has already replaced it.
+// It returns the token to retry the request with.
+func (c *boundaryClient) renewRejectedToken(ctx context.Context, rejected string) (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if current := c.Client.Token(); current != rejected {
+		return current, nil
+	}
+
+	if err := c.authenticateLocked(ctx); err != nil {
+		return "", err
+	}
+
+	return c.Client.Token(), nil
+}
+
+func (c *boundaryClient) markUsed() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.lastUsed = time.Now()
+}
+
+// reauthTransport keeps the client's auth token fresh. It logs in
+// again shortly before the token expires, and retries a request
+// once with a new token if Boundary answers 401.
+type reauthTransport struct {
+	base   http.RoundTripper
+	client *boundaryClient
+}
+
+func (t *reauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := t.client.refreshToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	client.SetToken(fmt.Sprint(authenticationResult.Attributes["token"]))
+	resp, err := t.base.RoundTrip(withToken(req, token, body))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.client.markUsed()
+		return resp, nil
+	}
+
+	renewed, err := t.client.renewRejectedToken(ctx, token)
+	if err != nil {
+		// Surface Boundary's original 401 rather than the login failure
+		return resp, nil
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	resp, err = t.base.RoundTrip(withToken(req, renewed, body))
+	if err == nil && resp.StatusCode != http.StatusUnauthorized {
+		t.client.markUsed()
+	}
+
+	return resp, err
+}
+
+// withToken clones req with the given bearer token and a fresh
+// copy of body.
+func withToken(req *http.Request, token string, body []byte) *http.Request {
+	out := req.Clone(req.Context())
+	out.Header.Set("authorization", "Bearer "+token)
+
+	if body != nil {
+		out.Body = ioutil.NopCloser(bytes.NewReader(body))
+		out.ContentLength = int64(len(body))
+	}
 
-	return &boundaryClient{client}, nil
+	return out
 }
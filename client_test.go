@@ -0,0 +1,629 @@
+package boundarysecrets
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/boundary/api/recovery"
+	"github.com/hashicorp/boundary/api/users"
+	wrapping "github.com/hashicorp/go-kms-wrapping/v2"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRecoveryKey    = "8fZBjCUfN0TzjEGLQldGY4+iE9AkOvCfjh7+p0GtRBQ="
+	testRecoveryKmsHcl = `
+kms "aead" {
+  purpose   = "recovery"
+  aead_type = "aes-gcm"
+  key       = "` + testRecoveryKey + `"
+  key_id    = "global_recovery"
+}
+`
+)
+
+// fakeBoundary is a minimal Boundary controller that issues
+// numbered auth tokens and serves a single user and the
+// password account Vault logs in with.
+type fakeBoundary struct {
+	sync.Mutex
+
+	issued     int
+	valid      []string
+	expiration time.Time
+
+	// password, when set, is required to authenticate
+	password string
+
+	// recovery, when set, accepts recovery tokens it signed
+	recovery wrapping.Wrapper
+
+	// sessions are returned by a session list, and cancelled
+	// records the sessions cancelled since
+	sessions      []string
+	sessionFilter string
+	cancelled     []string
+
+	// accountPasswords records passwords set by an administrator
+	accountPasswords map[string]string
+
+	// roles, when set, are the only roles that can be read, and
+	// rolePrincipals and roleGrants hold each role's principals
+	// and grants
+	roles          []string
+	rolePrincipals map[string][]string
+	roleGrants     map[string][]string
+
+	// groups holds the members of each group that exists
+	groups map[string][]string
+
+	// deleted records the paths of resources deleted, and
+	// missing holds paths that are deleted already
+	deleted []string
+	missing []string
+
+	// unavailable holds paths the controller fails to serve
+	unavailable []string
+
+	// workerTags records the API tags added to workers, and
+	// workerAuthToken the last worker-led registration request
+	workerTags      map[string][]string
+	workerAuthToken string
+
+	// workerNames are the names of existing workers, and
+	// workerFilter the filter of the last worker list
+	workerNames  []string
+	workerFilter string
+
+	// activated holds the workers that have reported their status
+	activated []string
+
+	// caScope records the scope of the last worker certificate
+	// authority request, and caVersion counts reinitializations
+	caScope   string
+	caVersion int
+
+	// scopeNames records the names of scopes created
+	scopeNames []string
+
+	// listItems, when set, holds the IDs returned by a list of each
+	// collection, and listFilters and listScopes the filter and
+	// scope each was listed with
+	listItems   map[string][]string
+	listFilters map[string]string
+	listScopes  map[string]string
+
+	// sessionToken records the auth token the last session was
+	// authorized with
+	sessionToken string
+}
+
+func (f *fakeBoundary) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if strings.HasSuffix(r.URL.Path, ":authenticate") {
+		var body struct {
+			Attributes map[string]string `json:"attributes"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if f.password != "" && body.Attributes["password"] != f.password {
+			writeFakeError(w, http.StatusUnauthorized, "Unauthenticated")
+			return
+		}
+
+		f.issued++
+		token := fmt.Sprintf("at_token%d", f.issued)
+		f.valid = append(f.valid, token)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"command": "login",
+			"attributes": map[string]interface{}{
+				"id":              fmt.Sprintf("at_%d", f.issued),
+				"token":           token,
+				"expiration_time": f.expiration.Format(time.RFC3339),
+			},
+		})
+		return
+	}
+
+	if !f.authorized(r) {
+		writeFakeError(w, http.StatusUnauthorized, "Unauthenticated")
+		return
+	}
+
+	if strutil.StrListContains(f.unavailable, r.URL.Path) {
+		writeFakeError(w, http.StatusServiceUnavailable, "Unavailable")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodDelete:
+		if strutil.StrListContains(f.missing, r.URL.Path) {
+			writeFakeError(w, http.StatusNotFound, "NotFound")
+			return
+		}
+		f.deleted = append(f.deleted, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && f.listItems[r.URL.Path] != nil:
+		if f.listFilters == nil {
+			f.listFilters = make(map[string]string)
+			f.listScopes = make(map[string]string)
+		}
+		f.listFilters[r.URL.Path] = r.URL.Query().Get("filter")
+		f.listScopes[r.URL.Path] = r.URL.Query().Get("scope_id")
+		var items []map[string]interface{}
+		for _, id := range f.listItems[r.URL.Path] {
+			items = append(items, map[string]interface{}{"id": id, "version": 1})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	case strings.HasPrefix(r.URL.Path, "/v1/auth-tokens/"):
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":              strings.TrimPrefix(r.URL.Path, "/v1/auth-tokens/"),
+			"expiration_time": f.expiration.Format(time.RFC3339),
+		})
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/accounts":
+		var body struct {
+			Attributes map[string]interface{} `json:"attributes"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":             "acctpw_0987654321",
+			"version":        1,
+			"auth_method_id": authMethodId,
+			"attributes":     map[string]interface{}{"login_name": body.Attributes["login_name"]},
+		})
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/roles":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "r_0987654321",
+			"version": 1,
+		})
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/scopes":
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		name, _ := body["name"].(string)
+		f.scopeNames = append(f.scopeNames, name)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":       "o_1234567890",
+			"version":  1,
+			"type":     "org",
+			"name":     name,
+			"scope_id": body["scope_id"],
+		})
+	case r.URL.Path == "/v1/accounts":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []map[string]interface{}{
+				{"id": "acctpw_1234567890", "version": 1},
+			},
+		})
+	case r.URL.Path == "/v1/accounts/acctpw_1234567890":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "acctpw_1234567890",
+			"version": 1,
+		})
+	case r.URL.Path == "/v1/sessions":
+		f.sessionFilter = r.URL.Query().Get("filter")
+		var items []map[string]interface{}
+		for _, id := range f.sessions {
+			items = append(items, map[string]interface{}{"id": id, "version": 1})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	case strings.HasSuffix(r.URL.Path, ":cancel"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/sessions/"), ":cancel")
+		f.cancelled = append(f.cancelled, id)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "version": 2})
+	case strings.HasSuffix(r.URL.Path, ":authorize-session"):
+		f.sessionToken = strings.TrimPrefix(r.Header.Get("authorization"), "Bearer ")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"session_id":          "s_1234567890",
+			"target_id":           strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/targets/"), ":authorize-session"),
+			"scope":               map[string]interface{}{"id": "p_1234567890"},
+			"authorization_token": "authz_1234567890",
+			"endpoint":            "tcp://localhost:22",
+		})
+	case strings.HasSuffix(r.URL.Path, ":set-password"):
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/accounts/"), ":set-password")
+		if f.accountPasswords == nil {
+			f.accountPasswords = make(map[string]string)
+		}
+		f.accountPasswords[id] = body["password"].(string)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      id,
+			"version": 2,
+		})
+	case strings.HasPrefix(r.URL.Path, "/v1/accounts/") && !strings.Contains(r.URL.Path, ":"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/accounts/")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":             id,
+			"version":        1,
+			"auth_method_id": authMethodId,
+			"attributes": map[string]interface{}{
+				"login_name": "static-" + id,
+			},
+		})
+	case strings.HasPrefix(r.URL.Path, "/v1/roles/") && !strings.Contains(r.URL.Path, ":"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/roles/")
+		if f.roles != nil && !strutil.StrListContains(f.roles, id) {
+			writeFakeError(w, http.StatusNotFound, "NotFound")
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":            id,
+			"version":       1,
+			"scope_id":      "global",
+			"principal_ids": f.rolePrincipals[id],
+		})
+	case strings.HasPrefix(r.URL.Path, "/v1/roles/") && strings.HasSuffix(r.URL.Path, ":remove-principals"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/roles/"), ":remove-principals")
+		var body struct {
+			PrincipalIds []string `json:"principal_ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, p := range body.PrincipalIds {
+			f.rolePrincipals[id] = strutil.StrListDelete(f.rolePrincipals[id], p)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":            id,
+			"version":       2,
+			"principal_ids": f.rolePrincipals[id],
+		})
+	case strings.HasPrefix(r.URL.Path, "/v1/roles/") && strings.HasSuffix(r.URL.Path, ":add-grants"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/roles/"), ":add-grants")
+		var body struct {
+			GrantStrings []string `json:"grant_strings"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if f.roleGrants == nil {
+			f.roleGrants = make(map[string][]string)
+		}
+		f.roleGrants[id] = append(f.roleGrants[id], body.GrantStrings...)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":            id,
+			"version":       2,
+			"grant_strings": f.roleGrants[id],
+		})
+	case strings.HasPrefix(r.URL.Path, "/v1/groups/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/groups/")
+		action := ""
+		if i := strings.Index(id, ":"); i >= 0 {
+			id, action = id[:i], id[i+1:]
+		}
+		members, ok := f.groups[id]
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "NotFound")
+			return
+		}
+		var body struct {
+			MemberIds []string `json:"member_ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch action {
+		case "add-members":
+			members = append(members, body.MemberIds...)
+		case "remove-members":
+			for _, m := range body.MemberIds {
+				members = strutil.StrListDelete(members, m)
+			}
+		}
+		f.groups[id] = members
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":         id,
+			"version":    1,
+			"member_ids": members,
+		})
+	case r.URL.Path == "/v1/workers":
+		f.workerFilter = r.URL.Query().Get("filter")
+		var items []map[string]interface{}
+		for _, name := range f.workerNames {
+			if f.workerFilter == filterEquals("/item/name", name) {
+				items = append(items, map[string]interface{}{"id": "w_" + name, "name": name})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	case r.URL.Path == "/v1/workers:read-certificate-authority" || r.URL.Path == "/v1/workers:reinitialize-certificate-authority":
+		f.caScope = r.URL.Query().Get("scope_id")
+		if strings.HasSuffix(r.URL.Path, ":reinitialize-certificate-authority") {
+			f.caVersion++
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"certs": []map[string]interface{}{
+				{
+					"id":                fmt.Sprintf("roots_%d", f.caVersion),
+					"public_key_sha256": "d0b5d2a4",
+					"not_before_time":   "2022-01-01T00:00:00Z",
+					"not_after_time":    "2023-01-01T00:00:00Z",
+				},
+			},
+		})
+	case strings.HasPrefix(r.URL.Path, "/v1/workers/") && !strings.Contains(r.URL.Path, ":"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/workers/")
+		item := map[string]interface{}{"id": id, "version": 1}
+		if strutil.StrListContains(f.activated, id) {
+			item["last_status_time"] = time.Now().Format(time.RFC3339)
+		}
+		json.NewEncoder(w).Encode(item)
+	case r.URL.Path == "/v1/workers:create:controller-led":
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":                                    "w_1234567890",
+			"version":                               1,
+			"name":                                  body["name"],
+			"description":                           body["description"],
+			"controller_generated_activation_token": "neslat_1234567890",
+		})
+	case r.URL.Path == "/v1/workers:create:worker-led":
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.workerAuthToken, _ = body["worker_generated_auth_token"].(string)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":          "w_0987654321",
+			"version":     1,
+			"name":        body["name"],
+			"description": body["description"],
+		})
+	case strings.HasSuffix(r.URL.Path, ":add-worker-tags"):
+		var body struct {
+			ApiTags map[string][]string `json:"api_tags"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.workerTags = body.ApiTags
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":       strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/workers/"), ":add-worker-tags"),
+			"version":  2,
+			"api_tags": body.ApiTags,
+		})
+	case strings.HasSuffix(r.URL.Path, ":change-password"):
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["current_password"] != f.password {
+			writeFakeError(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		f.password = body["new_password"].(string)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "acctpw_1234567890",
+			"version": 2,
+		})
+	default:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":       "u_1234567890",
+			"version":  1,
+			"scope_id": "global",
+		})
+	}
+}
+
+func (f *fakeBoundary) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("authorization"), "Bearer ")
+
+	if f.recovery != nil && strings.HasPrefix(token, "r_") {
+		_, err := recovery.ParseRecoveryToken(r.Context(), f.recovery, token)
+		return err == nil
+	}
+
+	return strutil.StrListContains(f.valid, token)
+}
+
+func writeFakeError(w http.ResponseWriter, status int, kind string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"kind":    kind,
+		"message": kind,
+	})
+}
+
+func (f *fakeBoundary) expireTokens() {
+	f.Lock()
+	defer f.Unlock()
+
+	f.valid = nil
+}
+
+func newTestClient(t *testing.T, fake *fakeBoundary) *boundaryClient {
+	t.Helper()
+
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	c, err := newClient(&boundaryConfig{
+		LoginName:    loginName,
+		Password:     Password,
+		Addr:         srv.URL,
+		AuthMethodId: authMethodId,
+	})
+	require.NoError(t, err)
+
+	return c
+}
+
+// configureTestBackend points the backend at a fake controller.
+func configureTestBackend(t *testing.T, b *boundaryBackend, s logical.Storage, fake *fakeBoundary) {
+	t.Helper()
+
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	err := testConfigCreate(t, b, s, map[string]interface{}{
+		"login_name":        loginName,
+		"password":          Password,
+		"addr":              srv.URL,
+		"auth_method_id":    authMethodId,
+		"verify_connection": false,
+	})
+	require.NoError(t, err)
+}
+
+func TestClientTokenRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Retry After Unauthorized", func(t *testing.T) {
+		fake := &fakeBoundary{expiration: time.Now().Add(time.Hour)}
+		c := newTestClient(t, fake)
+		require.Equal(t, "at_token1", c.Token())
+
+		fake.expireTokens()
+
+		urr, err := users.NewClient(c.Client).Read(ctx, "u_1234567890")
+		require.NoError(t, err)
+		require.Equal(t, "u_1234567890", urr.Item.Id)
+		require.Equal(t, "at_token2", c.Token())
+	})
+
+	t.Run("Refresh Before Expiry", func(t *testing.T) {
+		fake := &fakeBoundary{expiration: time.Now().Add(time.Minute)}
+		c := newTestClient(t, fake)
+		require.Equal(t, "at_token1", c.Token())
+
+		_, err := users.NewClient(c.Client).Read(ctx, "u_1234567890")
+		require.NoError(t, err)
+		require.Equal(t, "at_token2", c.Token())
+	})
+
+	t.Run("Reuse Valid Token", func(t *testing.T) {
+		fake := &fakeBoundary{expiration: time.Now().Add(time.Hour)}
+		c := newTestClient(t, fake)
+
+		for i := 0; i < 3; i++ {
+			_, err := users.NewClient(c.Client).Read(ctx, "u_1234567890")
+			require.NoError(t, err)
+		}
+		require.Equal(t, "at_token1", c.Token())
+	})
+}
+
+func TestClientTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(&fakeBoundary{expiration: time.Now().Add(time.Hour)})
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	caCert := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: srv.Certificate().Raw,
+	})
+
+	config := &boundaryConfig{
+		LoginName:    loginName,
+		Password:     Password,
+		Addr:         srv.URL,
+		AuthMethodId: authMethodId,
+	}
+
+	t.Run("Unknown CA", func(t *testing.T) {
+		_, err := newClient(config)
+		require.Error(t, err)
+	})
+
+	t.Run("Configured CA", func(t *testing.T) {
+		withCA := *config
+		withCA.CACert = string(caCert)
+
+		_, err := newClient(&withCA)
+		require.NoError(t, err)
+	})
+
+	t.Run("Skip Verify", func(t *testing.T) {
+		insecure := *config
+		insecure.TLSSkipVerify = true
+
+		_, err := newClient(&insecure)
+		require.NoError(t, err)
+	})
+}
+
+func TestClientAuthToken(t *testing.T) {
+	ctx := context.Background()
+
+	fake := &fakeBoundary{
+		expiration: time.Now().Add(time.Hour),
+		valid:      []string{"at_1234567890_secret"},
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	t.Run("Invalid Format", func(t *testing.T) {
+		_, err := authTokenId("1234567890")
+		require.Error(t, err)
+
+		id, err := authTokenId("at_1234567890_secret_with_underscores")
+		require.NoError(t, err)
+		require.Equal(t, "at_1234567890", id)
+	})
+
+	t.Run("Valid Token", func(t *testing.T) {
+		c, err := newClient(&boundaryConfig{
+			Addr:      srv.URL,
+			AuthToken: "at_1234567890_secret",
+		})
+		require.NoError(t, err)
+		require.WithinDuration(t, fake.expiration, c.tokenExpiration(), time.Second)
+
+		_, err = users.NewClient(c.Client).Read(ctx, "u_1234567890")
+		require.NoError(t, err)
+	})
+
+	t.Run("Rejected Token", func(t *testing.T) {
+		_, err := newClient(&boundaryConfig{
+			Addr:      srv.URL,
+			AuthToken: "at_0987654321_wrong",
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestClientRecoveryKms(t *testing.T) {
+	ctx := context.Background()
+
+	wrapper, err := recoveryKmsWrapper(ctx, testRecoveryKmsHcl)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(&fakeBoundary{recovery: wrapper})
+	defer srv.Close()
+
+	t.Run("Parse Errors", func(t *testing.T) {
+		_, err := recoveryKmsWrapper(ctx, `kms "aead" { purpose = "root" key = "`+testRecoveryKey+`" }`)
+		require.Error(t, err)
+
+		_, err = recoveryKmsWrapper(ctx, `kms "awskms" { purpose = "recovery" }`)
+		require.Error(t, err)
+
+		_, err = recoveryKmsWrapper(ctx, `kms "aead" { purpose = ["root", "recovery"] }`)
+		require.Error(t, err)
+	})
+
+	t.Run("Recovery Requests", func(t *testing.T) {
+		c, err := newClient(&boundaryConfig{
+			Addr:           srv.URL,
+			RecoveryKmsHcl: testRecoveryKmsHcl,
+		})
+		require.NoError(t, err)
+
+		_, err = users.NewClient(c.Client).Read(ctx, "u_1234567890")
+		require.NoError(t, err)
+	})
+
+	t.Run("Wrong Key", func(t *testing.T) {
+		c, err := newClient(&boundaryConfig{
+			Addr:           srv.URL,
+			RecoveryKmsHcl: `kms "aead" { purpose = "recovery" key = "` + strings.Repeat("A", 43) + `=" }`,
+		})
+		require.NoError(t, err)
+
+		_, err = users.NewClient(c.Client).Read(ctx, "u_1234567890")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,98 @@
+package boundarysecrets
+
+import (
+	"errors"
+	"net/http"
+
+	boundary "github.com/hashicorp/boundary/api"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// errorKind describes where a failure came from, which decides
+// the status Vault returns for it.
+type errorKind int
+
+const (
+	// errorKindLocal is a failure inside Vault or the plugin,
+	// such as storage or password generation.
+	errorKindLocal errorKind = iota
+
+	// errorKindUser is a request Boundary rejected with a 4xx,
+	// which will fail again unless the request or role changes.
+	errorKindUser
+
+	// errorKindRetryable is a 5xx from Boundary, which may
+	// succeed if the request is retried.
+	errorKindRetryable
+)
+
+// boundaryError wraps a failure with its errorKind.
+type boundaryError struct {
+	kind errorKind
+	err  error
+}
+
+func (e *boundaryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *boundaryError) Unwrap() error {
+	return e.err
+}
+
+// newUserError marks err as caused by the request or role, so
+// that it is reported to the caller as a bad request.
+func newUserError(err error) error {
+	return &boundaryError{kind: errorKindUser, err: err}
+}
+
+// classifyError wraps err in a boundaryError, using the status of
+// any Boundary API error it contains. Errors that are already
+// classified are returned unchanged.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var be *boundaryError
+	if errors.As(err, &be) {
+		return err
+	}
+
+	kind := errorKindLocal
+	if apiErr := boundary.AsServerError(err); apiErr != nil && apiErr.Response() != nil {
+		switch status := apiErr.Response().StatusCode(); {
+		case status >= 500:
+			kind = errorKindRetryable
+		case status >= 400:
+			kind = errorKindUser
+		}
+	}
+
+	return &boundaryError{kind: kind, err: err}
+}
+
+// errorResponse converts an error into what a path handler should
+// return: a 400 for requests Boundary rejected, a 502 for Boundary
+// server errors, and a 500 for anything else.
+func errorResponse(err error) (*logical.Response, error) {
+	var be *boundaryError
+	if !errors.As(classifyError(err), &be) {
+		return nil, err
+	}
+
+	switch be.kind {
+	case errorKindUser:
+		return logical.ErrorResponse(err.Error()), nil
+	case errorKindRetryable:
+		return nil, logical.CodedError(http.StatusBadGateway, err.Error())
+	default:
+		return nil, err
+	}
+}
+
+// isNotFound reports whether err is a 404 from Boundary.
+func isNotFound(err error) bool {
+	apiErr := boundary.AsServerError(err)
+	return apiErr != nil && apiErr.Response() != nil && apiErr.Response().StatusCode() == http.StatusNotFound
+}
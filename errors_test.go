@@ -0,0 +1,65 @@
+package boundarysecrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	boundary "github.com/hashicorp/boundary/api"
+	"github.com/hashicorp/boundary/api/users"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// boundaryStatusError returns the error the Boundary API client
+// produces when the controller answers with status.
+func boundaryStatusError(t *testing.T, status int) error {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeFakeError(w, status, http.StatusText(status))
+	}))
+	defer srv.Close()
+
+	client, err := boundary.NewClient(&boundary.Config{Addr: srv.URL})
+	require.NoError(t, err)
+
+	_, err = users.NewClient(client).Read(context.Background(), "u_1234567890")
+	require.Error(t, err)
+
+	return fmt.Errorf("error reading user: %w", err)
+}
+
+func TestErrorResponse(t *testing.T) {
+	t.Run("Boundary 4xx", func(t *testing.T) {
+		resp, err := errorResponse(boundaryStatusError(t, http.StatusNotFound))
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("Boundary 5xx", func(t *testing.T) {
+		resp, err := errorResponse(boundaryStatusError(t, http.StatusInternalServerError))
+		require.Nil(t, resp)
+
+		var coded logical.HTTPCodedError
+		require.True(t, errors.As(err, &coded))
+		require.Equal(t, http.StatusBadGateway, coded.Code())
+	})
+
+	t.Run("User Error", func(t *testing.T) {
+		resp, err := errorResponse(newUserError(errors.New("invalid login name")))
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("Local Error", func(t *testing.T) {
+		local := errors.New("storage unavailable")
+
+		resp, err := errorResponse(local)
+		require.Nil(t, resp)
+		require.ErrorIs(t, err, local)
+	})
+}
@@ -0,0 +1,165 @@
+package boundarysecrets
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/vault/sdk/helper/template"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/ryanuber/go-glob"
+)
+
+const (
+	defaultUsernameTemplate   = `vault-role-{{.RoleName}}-{{random 8 | lowercase}}`
+	defaultWorkerNameTemplate = `vault-worker-{{.RoleName}}-{{random 8 | lowercase}}`
+)
+
+// loginNameRegex matches the login names Boundary's password auth
+// method accepts.
+var loginNameRegex = regexp.MustCompile(`^[a-z0-9.-]+$`)
+
+// nameTemplateData is what username and display name templates
+// can refer to.
+type nameTemplateData struct {
+	RoleName    string
+	DisplayName string
+	EntityID    string
+	Metadata    map[string]string
+}
+
+// newNameTemplateData collects the template data for a request.
+func (b *boundaryBackend) newNameTemplateData(req *logical.Request, roleName string) (*nameTemplateData, error) {
+	data := &nameTemplateData{
+		RoleName:    roleName,
+		DisplayName: req.DisplayName,
+		EntityID:    req.EntityID,
+	}
+
+	if req.EntityID != "" {
+		entity, err := b.System().EntityInfo(req.EntityID)
+		if err != nil {
+			return nil, fmt.Errorf("error reading entity: %w", err)
+		}
+		if entity != nil {
+			data.Metadata = entity.Metadata
+		}
+	}
+
+	return data, nil
+}
+
+// renderNameTemplate renders a username or display name template.
+func renderNameTemplate(tmpl string, data *nameTemplateData) (string, error) {
+	t, err := template.NewTemplate(template.Template(tmpl))
+	if err != nil {
+		return "", fmt.Errorf("unable to parse template: %w", err)
+	}
+
+	name, err := t.Generate(data)
+	if err != nil {
+		return "", fmt.Errorf("unable to render template: %w", err)
+	}
+
+	return name, nil
+}
+
+// validateLoginName checks a login name against Boundary's rules.
+func validateLoginName(name string) error {
+	if !loginNameRegex.MatchString(name) {
+		return fmt.Errorf("login name %q must only contain lowercase letters, digits, periods and hyphens", name)
+	}
+
+	return nil
+}
+
+// accountNames renders the login name and user name for a new
+// account from the role's templates.
+func (r *boundaryRoleEntry) accountNames(data *nameTemplateData) (string, string, error) {
+	usernameTemplate := r.UsernameTemplate
+	if usernameTemplate == "" {
+		usernameTemplate = defaultUsernameTemplate
+	}
+
+	loginName, err := renderNameTemplate(usernameTemplate, data)
+	if err != nil {
+		return "", "", fmt.Errorf("error generating login name: %w", err)
+	}
+
+	if err := validateLoginName(loginName); err != nil {
+		return "", "", err
+	}
+
+	if r.DisplayNameTemplate == "" {
+		return loginName, loginName, nil
+	}
+
+	userName, err := renderNameTemplate(r.DisplayNameTemplate, data)
+	if err != nil {
+		return "", "", fmt.Errorf("error generating user name: %w", err)
+	}
+
+	if userName == "" {
+		return "", "", fmt.Errorf("display name template rendered an empty name")
+	}
+
+	return loginName, userName, nil
+}
+
+// checkDisplayNameUnique renders the role's display name template
+// twice and returns an error if both renders are the same. Boundary
+// user names must be unique, and rollback finds users by name, so
+// every request needs a name of its own.
+func (r *boundaryRoleEntry) checkDisplayNameUnique(data *nameTemplateData) error {
+	first, err := renderNameTemplate(r.DisplayNameTemplate, data)
+	if err != nil {
+		return fmt.Errorf("error generating user name: %w", err)
+	}
+
+	second, err := renderNameTemplate(r.DisplayNameTemplate, data)
+	if err != nil {
+		return fmt.Errorf("error generating user name: %w", err)
+	}
+
+	if first == second {
+		return fmt.Errorf("display name template must render a unique name for each request, for example with {{random 8 | lowercase}}")
+	}
+
+	return nil
+}
+
+// generateWorkerName renders a name for a new worker from the
+// role's worker name template.
+func (r *boundaryRoleEntry) generateWorkerName(data *nameTemplateData) (string, error) {
+	workerNameTemplate := r.WorkerNameTemplate
+	if workerNameTemplate == "" {
+		workerNameTemplate = defaultWorkerNameTemplate
+	}
+
+	name, err := renderNameTemplate(workerNameTemplate, data)
+	if err != nil {
+		return "", fmt.Errorf("error generating worker name: %w", err)
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("worker name template rendered an empty name")
+	}
+
+	return name, nil
+}
+
+// checkWorkerName returns an error unless the name matches one of
+// the role's allowed worker name patterns. Any name is allowed when
+// the role has no patterns.
+func (r *boundaryRoleEntry) checkWorkerName(name string) error {
+	if len(r.AllowedWorkerNamePatterns) == 0 {
+		return nil
+	}
+
+	for _, pattern := range r.AllowedWorkerNamePatterns {
+		if glob.Glob(pattern, name) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("worker name %q is not allowed by role %q", name, r.Name)
+}
@@ -0,0 +1,113 @@
+package boundarysecrets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountNames(t *testing.T) {
+	data := &nameTemplateData{
+		RoleName:    "ci",
+		DisplayName: "approle",
+		EntityID:    "8d5f1a1e-6ac4-4f1a-9a3b-1bd2c3a4b5c6",
+		Metadata:    map[string]string{"team": "platform"},
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		role := &boundaryRoleEntry{Name: "ci"}
+
+		loginName, userName, err := role.accountNames(data)
+		require.NoError(t, err)
+		require.Regexp(t, `^vault-role-ci-[a-z0-9]{8}$`, loginName)
+		require.Equal(t, loginName, userName)
+	})
+
+	t.Run("Templates", func(t *testing.T) {
+		role := &boundaryRoleEntry{
+			Name:                "ci",
+			UsernameTemplate:    `{{.Metadata.team}}-{{.RoleName}}-{{random 4 | lowercase}}`,
+			DisplayNameTemplate: `{{.DisplayName}} ({{.EntityID}}) {{random 4 | lowercase}}`,
+		}
+
+		loginName, userName, err := role.accountNames(data)
+		require.NoError(t, err)
+		require.Regexp(t, `^platform-ci-[a-z0-9]{4}$`, loginName)
+		require.Regexp(t, `^approle \(8d5f1a1e-6ac4-4f1a-9a3b-1bd2c3a4b5c6\) [a-z0-9]{4}$`, userName)
+	})
+
+	t.Run("Empty Display Name", func(t *testing.T) {
+		role := &boundaryRoleEntry{
+			Name:                "ci",
+			DisplayNameTemplate: `{{if .Metadata.owner}}{{.Metadata.owner}}{{end}}`,
+		}
+
+		_, _, err := role.accountNames(data)
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid Login Name", func(t *testing.T) {
+		role := &boundaryRoleEntry{
+			Name:             "ci",
+			UsernameTemplate: `{{.DisplayName}}_{{.RoleName}}`,
+		}
+
+		_, _, err := role.accountNames(data)
+		require.Error(t, err)
+	})
+}
+
+func TestWorkerNames(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	fake := &fakeBoundary{
+		expiration:  time.Now().Add(time.Hour),
+		workerNames: []string{"edge-taken"},
+	}
+	configureTestBackend(t, b, s, fake)
+
+	_, err := testTokenRoleCreate(t, b, s, workerRoleName, map[string]interface{}{
+		"scope_id":                     scope_id,
+		"role_type":                    "worker",
+		"worker_name_template":         `edge-{{random 6 | lowercase}}`,
+		"allowed_worker_name_patterns": "edge-*",
+	})
+	require.NoError(t, err)
+
+	t.Run("Generated Name", func(t *testing.T) {
+		resp, err := testWorkerCreds(t, b, s, map[string]interface{}{})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+		require.Regexp(t, `^edge-[a-z0-9]{6}$`, resp.Data["worker_name"])
+		require.Contains(t, fake.workerFilter, `"/item/name" == "edge-`)
+	})
+
+	t.Run("Name Not Allowed", func(t *testing.T) {
+		resp, err := testWorkerCreds(t, b, s, map[string]interface{}{
+			"worker_name": "core-1",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "not allowed")
+	})
+
+	t.Run("Name Taken", func(t *testing.T) {
+		resp, err := testWorkerCreds(t, b, s, map[string]interface{}{
+			"worker_name": "edge-taken",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "already exists")
+	})
+
+	t.Run("Invalid Template", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, workerRoleName, map[string]interface{}{
+			"scope_id":             scope_id,
+			"role_type":            "worker",
+			"worker_name_template": `{{.RoleName`,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+}
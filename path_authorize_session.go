@@ -0,0 +1,170 @@
+package boundarysecrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/boundary/api/targets"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathAuthorizeSession extends the Vault API with an
+// `/authorize-session` endpoint for a role, which brokers a
+// Boundary session on behalf of the caller.
+func pathAuthorizeSession(b *boundaryBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "authorize-session/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the role",
+				Required:    true,
+			},
+			"target_id": {
+				Type:        framework.TypeString,
+				Description: "ID of the Boundary target to authorize a session for",
+			},
+			"target_name": {
+				Type:        framework.TypeString,
+				Description: "Name of the Boundary target to authorize a session for. Requires target_scope_id.",
+			},
+			"target_scope_id": {
+				Type:        framework.TypeString,
+				Description: "Scope ID of the target named by target_name",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathAuthorizeSessionWrite,
+			},
+		},
+		HelpSynopsis:    pathAuthorizeSessionHelpSyn,
+		HelpDescription: pathAuthorizeSessionHelpDesc,
+	}
+}
+
+func (b *boundaryBackend) pathAuthorizeSessionWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("name").(string)
+	targetId := d.Get("target_id").(string)
+	targetName := d.Get("target_name").(string)
+	targetScopeId := d.Get("target_scope_id").(string)
+
+	if targetId == "" && (targetName == "" || targetScopeId == "") {
+		return logical.ErrorResponse("either target_id, or target_name and target_scope_id, must be set"), nil
+	}
+
+	if targetId != "" && targetName != "" {
+		return logical.ErrorResponse("target_id and target_name are mutually exclusive"), nil
+	}
+
+	roleEntry, err := b.getRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving role: %w", err)
+	}
+
+	if roleEntry == nil {
+		return nil, errors.New("error retrieving role: role is nil")
+	}
+
+	if roleEntry.RoleType != "user" {
+		return logical.ErrorResponse("sessions can only be authorized for user roles"), nil
+	}
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if targetId == "" {
+		targetId, err = findTarget(ctx, client, targetScopeId, targetName)
+		if err != nil {
+			return errorResponse(err)
+		}
+	}
+
+	if !strutil.StrListContains(roleEntry.AllowedTargetIds, targetId) {
+		return logical.ErrorResponse("target %q is not allowed by role %q", targetId, roleName), nil
+	}
+
+	// The session belongs to a user generated for this request, which
+	// needs its own auth token to authorize it
+	userRole := *roleEntry
+	userRole.CredentialType = credentialTypeAuthToken
+
+	account, err := b.createAccount(ctx, req, &userRole)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	userClient := client.authClient.Clone()
+	userClient.SetToken(account.AuthToken)
+
+	sar, err := targets.NewClient(userClient).AuthorizeSession(ctx, targetId)
+	if err != nil {
+		if derr := deleteToken(ctx, b.Logger(), client, account.AccountId, account.UserId, account.EphemeralRoleId, account.AuthTokenId); derr != nil {
+			b.Logger().Error("error removing user after failed session authorization", "user_id", account.UserId, "error", derr)
+		}
+		return errorResponse(fmt.Errorf("error authorizing session for target %q: %w", targetId, err))
+	}
+
+	// Revoking the lease cancels the session and removes the user
+	resp := b.Secret(Account).Response(map[string]interface{}{
+		"session_id":          sar.Item.SessionId,
+		"target_id":           sar.Item.TargetId,
+		"authorization_token": sar.Item.AuthorizationToken,
+		"endpoint":            sar.Item.Endpoint,
+		"credentials":         sar.Item.Credentials,
+		"user_id":             account.UserId,
+		"account_id":          account.AccountId,
+		"login_name":          account.LoginName,
+	}, map[string]interface{}{
+		"account_id":        account.AccountId,
+		"user_id":           account.UserId,
+		"ephemeral_role_id": account.EphemeralRoleId,
+		"boundary_groups":   account.BoundaryGroups,
+		"auth_token_id":     account.AuthTokenId,
+		"ttl":               roleEntry.TTL.Seconds(),
+		"max_ttl":           roleEntry.MaxTTL.Seconds(),
+	})
+
+	if roleEntry.TTL > 0 {
+		resp.Secret.TTL = roleEntry.TTL
+	}
+
+	if roleEntry.MaxTTL > 0 {
+		resp.Secret.MaxTTL = roleEntry.MaxTTL
+	}
+
+	return resp, nil
+}
+
+// findTarget looks up the ID of the target with the given name.
+func findTarget(ctx context.Context, c *boundaryClient, scopeId string, name string) (string, error) {
+	filter := filterEquals("/item/name", name)
+	tlr, err := targets.NewClient(c.Client).List(ctx, scopeId, targets.WithFilter(filter))
+	if err != nil {
+		return "", fmt.Errorf("error listing targets: %w", err)
+	}
+
+	if len(tlr.Items) != 1 {
+		return "", fmt.Errorf("expected one target named %q in scope %q, found %d", name, scopeId, len(tlr.Items))
+	}
+
+	return tlr.Items[0].Id, nil
+}
+
+const pathAuthorizeSessionHelpSyn = `
+Authorize a Boundary session to a target using a Vault role.
+`
+
+const pathAuthorizeSessionHelpDesc = `
+This path generates a Boundary user from a role and authorizes
+a session to the given target as that user. The target must be
+listed in the role's allowed_target_ids. The response contains
+the session authorization token, the endpoint and any brokered
+credentials. Revoking the lease cancels the session and deletes
+the user.
+`
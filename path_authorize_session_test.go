@@ -0,0 +1,95 @@
+package boundarysecrets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuthorizeSession checks that a session is only authorized
+// for targets the role allows, and as the user generated for it.
+func TestAuthorizeSession(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	fake := &fakeBoundary{expiration: time.Now().Add(time.Hour)}
+	configureTestBackend(t, b, s, fake)
+
+	_, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+		"boundary_roles":     boundary_roles,
+		"scope_id":           scope_id,
+		"auth_method_id":     auth_method_id,
+		"role_type":          roleType,
+		"ttl":                testTTL,
+		"allowed_target_ids": "ttcp_1234567890,ttcp_0987654321",
+	})
+	require.NoError(t, err)
+
+	t.Run("Read Allowed Targets", func(t *testing.T) {
+		resp, err := testTokenRoleRead(t, b, s)
+		require.NoError(t, err)
+		require.Equal(t, []string{"ttcp_1234567890", "ttcp_0987654321"}, resp.Data["allowed_target_ids"])
+	})
+
+	t.Run("Missing Target", func(t *testing.T) {
+		resp, err := testAuthorizeSession(t, b, s, map[string]interface{}{
+			"target_name": "ssh",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("Target Not Allowed", func(t *testing.T) {
+		resp, err := testAuthorizeSession(t, b, s, map[string]interface{}{
+			"target_id": "ttcp_1111111111",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "not allowed")
+	})
+
+	t.Run("Authorize Session", func(t *testing.T) {
+		fake.listItems = map[string][]string{"/v1/targets": {"ttcp_1234567890"}}
+
+		resp, err := testAuthorizeSession(t, b, s, map[string]interface{}{
+			"target_name":     "ssh",
+			"target_scope_id": "p_1234567890",
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+
+		// The target is looked up by name in the requested scope
+		require.Equal(t, "p_1234567890", fake.listScopes["/v1/targets"])
+		require.Equal(t, `"/item/name" == "ssh"`, fake.listFilters["/v1/targets"])
+
+		require.Equal(t, "ttcp_1234567890", resp.Data["target_id"])
+		require.Equal(t, "s_1234567890", resp.Data["session_id"])
+		require.Equal(t, "authz_1234567890", resp.Data["authorization_token"])
+		require.Equal(t, "tcp://localhost:22", resp.Data["endpoint"])
+
+		// The session is authorized as the generated user, not Vault
+		require.Equal(t, "at_token2", fake.sessionToken)
+		require.Equal(t, "at_2", resp.Secret.InternalData["auth_token_id"])
+
+		renew, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RenewOperation,
+			Secret:    resp.Secret,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, time.Duration(testTTL)*time.Second, renew.Secret.TTL)
+	})
+
+}
+
+func testAuthorizeSession(t *testing.T, b *boundaryBackend, s logical.Storage, d map[string]interface{}) (*logical.Response, error) {
+	t.Helper()
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "authorize-session/" + roleName,
+		Data:      d,
+		Storage:   s,
+	})
+}
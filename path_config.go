@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -17,10 +18,35 @@ const (
 // boundaryConfig includes the minimum configuration
 // required to instantiate a new boundary client.
 type boundaryConfig struct {
-	LoginName    string `json:"login_name"`
-	Password     string `json:"password"`
-	Addr         string `json:"addr"`
-	AuthMethodId string `json:"auth_method_id"`
+	LoginName      string `json:"login_name"`
+	Password       string `json:"password"`
+	Addr           string `json:"addr"`
+	AuthMethodId   string `json:"auth_method_id"`
+	PasswordPolicy string `json:"password_policy"`
+
+	// RotationPeriod and RotationSchedule control automatic
+	// rotation of Password. At most one of them is set.
+	RotationPeriod   time.Duration `json:"rotation_period"`
+	RotationSchedule string        `json:"rotation_schedule"`
+
+	// TLS settings for the connection to the Boundary controller.
+	// Certificates and the key are PEM encoded.
+	CACert        string `json:"ca_cert"`
+	CAPath        string `json:"ca_path"`
+	ClientCert    string `json:"client_cert"`
+	ClientKey     string `json:"client_key"`
+	TLSServerName string `json:"tls_server_name"`
+	TLSSkipVerify bool   `json:"tls_skip_verify"`
+
+	// AuthToken is a pre-issued Boundary auth token used instead
+	// of logging in with LoginName and Password.
+	AuthToken              string        `json:"auth_token"`
+	AuthTokenExpiryWarning time.Duration `json:"auth_token_expiry_warning"`
+
+	// RecoveryKmsHcl is a Boundary `kms "aead"` stanza with the
+	// recovery purpose. When set, Vault authenticates every request
+	// with a recovery token instead of an account.
+	RecoveryKmsHcl string `json:"recovery_kms_hcl"`
 }
 
 // pathConfig extends the Vault API with a `/config`
@@ -69,6 +95,125 @@ func pathConfig(b *boundaryBackend) *framework.Path {
 					Sensitive: false,
 				},
 			},
+			"password_policy": {
+				Type:        framework.TypeString,
+				Description: "The Vault password policy used to generate a new password when rotating the root credential, and for generated accounts whose role has no password_policy, and for static role accounts",
+				Required:    false,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "Password Policy",
+					Sensitive: false,
+				},
+			},
+			"rotation_period": {
+				Type:        framework.TypeDurationSecond,
+				Description: "How often Vault rotates the password. Cannot be used with rotation_schedule",
+				Required:    false,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "Rotation Period",
+					Sensitive: false,
+				},
+			},
+			"rotation_schedule": {
+				Type:        framework.TypeString,
+				Description: "Cron-style schedule on which Vault rotates the password. Cannot be used with rotation_period",
+				Required:    false,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "Rotation Schedule",
+					Sensitive: false,
+				},
+			},
+			"ca_cert": {
+				Type:        framework.TypeString,
+				Description: "PEM encoded CA certificate used to verify the Boundary controller's certificate",
+				Required:    false,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "CA Certificate",
+					Sensitive: false,
+				},
+			},
+			"ca_path": {
+				Type:        framework.TypeString,
+				Description: "Path on the Vault server to a directory of PEM encoded CA certificates used to verify the Boundary controller's certificate",
+				Required:    false,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "CA Path",
+					Sensitive: false,
+				},
+			},
+			"client_cert": {
+				Type:        framework.TypeString,
+				Description: "PEM encoded client certificate presented to the Boundary controller",
+				Required:    false,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "Client Certificate",
+					Sensitive: false,
+				},
+			},
+			"client_key": {
+				Type:        framework.TypeString,
+				Description: "PEM encoded private key for client_cert",
+				Required:    false,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "Client Key",
+					Sensitive: true,
+				},
+			},
+			"tls_server_name": {
+				Type:        framework.TypeString,
+				Description: "Name to use as the SNI host when connecting to the Boundary controller",
+				Required:    false,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "TLS Server Name",
+					Sensitive: false,
+				},
+			},
+			"tls_skip_verify": {
+				Type:        framework.TypeBool,
+				Description: "Skip verification of the Boundary controller's certificate. Not recommended for production",
+				Required:    false,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "TLS Skip Verify",
+					Sensitive: false,
+				},
+			},
+			"auth_token": {
+				Type:        framework.TypeString,
+				Description: "A pre-issued Boundary auth token (at_...) Vault will use instead of login_name and password",
+				Required:    false,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "Auth Token",
+					Sensitive: true,
+				},
+			},
+			"auth_token_expiry_warning": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Warn on config reads when auth_token expires within this window",
+				Required:    false,
+				Default:     86400,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "Auth Token Expiry Warning",
+					Sensitive: false,
+				},
+			},
+			"verify_connection": {
+				Type:        framework.TypeBool,
+				Description: "Verify that Vault can log in to Boundary and list users and accounts before saving the configuration. A pre-issued auth_token is validated either way",
+				Required:    false,
+				Default:     true,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "Verify Connection",
+					Sensitive: false,
+				},
+			},
+			"recovery_kms_hcl": {
+				Type:        framework.TypeString,
+				Description: "HCL for a Boundary aead KMS with the recovery purpose, used instead of login_name and password",
+				Required:    false,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "Recovery KMS HCL",
+					Sensitive: true,
+				},
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.ReadOperation: &framework.PathOperation{
@@ -125,13 +270,81 @@ func (b *boundaryBackend) pathConfigRead(ctx context.Context, req *logical.Reque
 		return nil, err
 	}
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"login_name":     config.LoginName,
-			"addr":           config.Addr,
-			"auth_method_id": config.AuthMethodId,
-		},
-	}, nil
+	if config == nil {
+		return nil, nil
+	}
+
+	state, err := getRotationState(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	respData := map[string]interface{}{
+		"login_name":        config.LoginName,
+		"addr":              config.Addr,
+		"auth_method_id":    config.AuthMethodId,
+		"password_policy":   config.PasswordPolicy,
+		"rotation_period":   config.RotationPeriod.Seconds(),
+		"rotation_schedule": config.RotationSchedule,
+		"ca_cert":           config.CACert,
+		"ca_path":           config.CAPath,
+		"client_cert":       config.ClientCert,
+		"tls_server_name":   config.TLSServerName,
+		"tls_skip_verify":   config.TLSSkipVerify,
+
+		"auth_token_expiry_warning": config.AuthTokenExpiryWarning.Seconds(),
+	}
+
+	if !state.LastRotation.IsZero() {
+		respData["last_rotation"] = state.LastRotation
+	}
+
+	if !state.NextRotation.IsZero() {
+		respData["next_rotation"] = state.NextRotation
+	}
+
+	resp := &logical.Response{
+		Data: respData,
+	}
+
+	if config.AuthToken != "" {
+		id, _ := authTokenId(config.AuthToken)
+		respData["auth_token_id"] = id
+
+		client, err := b.getClient(ctx, req.Storage)
+		if err != nil {
+			resp.AddWarning(fmt.Sprintf("unable to read auth token: %s", err))
+			return resp, nil
+		}
+
+		expiration := client.tokenExpiration()
+		respData["auth_token_expiration"] = expiration
+
+		if warning := config.authTokenWarning(expiration); warning != "" {
+			resp.AddWarning(warning)
+		}
+	}
+
+	return resp, nil
+}
+
+// authTokenWarning returns a warning if a pre-issued auth token
+// expires within the configured warning window.
+func (c *boundaryConfig) authTokenWarning(expiration time.Time) string {
+	if c.AuthToken == "" || expiration.IsZero() {
+		return ""
+	}
+
+	remaining := time.Until(expiration)
+	if remaining > c.AuthTokenExpiryWarning {
+		return ""
+	}
+
+	if remaining <= 0 {
+		return fmt.Sprintf("auth token expired at %s", expiration.Format(time.RFC3339))
+	}
+
+	return fmt.Sprintf("auth token expires at %s, in %s", expiration.Format(time.RFC3339), remaining.Round(time.Second))
 }
 
 func (b *boundaryBackend) pathConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -149,9 +362,26 @@ func (b *boundaryBackend) pathConfigWrite(ctx context.Context, req *logical.Requ
 		config = new(boundaryConfig)
 	}
 
+	authToken, authTokenOk := data.GetOk("auth_token")
+	if authTokenOk {
+		config.AuthToken = authToken.(string)
+	}
+
+	if recoveryKmsHcl, ok := data.GetOk("recovery_kms_hcl"); ok {
+		config.RecoveryKmsHcl = recoveryKmsHcl.(string)
+	}
+
+	if config.AuthToken != "" && config.RecoveryKmsHcl != "" {
+		return logical.ErrorResponse("auth_token and recovery_kms_hcl are mutually exclusive"), nil
+	}
+
+	// A pre-issued auth token or recovery KMS replaces the password
+	// login credentials, so those are only required without one.
+	requireLogin := createOperation && config.AuthToken == "" && config.RecoveryKmsHcl == ""
+
 	if login_name, ok := data.GetOk("login_name"); ok {
 		config.LoginName = login_name.(string)
-	} else if !ok && createOperation {
+	} else if !ok && requireLogin {
 		return nil, fmt.Errorf("missing login_name in configuration")
 	}
 
@@ -163,7 +393,7 @@ func (b *boundaryBackend) pathConfigWrite(ctx context.Context, req *logical.Requ
 
 	if password, ok := data.GetOk("password"); ok {
 		config.Password = password.(string)
-	} else if !ok && createOperation {
+	} else if !ok && requireLogin {
 		return nil, fmt.Errorf("missing password in configuration")
 	}
 
@@ -173,10 +403,112 @@ func (b *boundaryBackend) pathConfigWrite(ctx context.Context, req *logical.Requ
 		} else {
 			return nil, fmt.Errorf("invalid auth_method_id type. Must be password auth method type")
 		}
-	} else if !ok && createOperation {
+	} else if !ok && requireLogin {
 		return nil, fmt.Errorf("missing auth_method_id in configuration ")
 	}
 
+	if config.AuthToken != "" {
+		if _, err := authTokenId(config.AuthToken); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	if config.RecoveryKmsHcl != "" {
+		if _, err := recoveryKmsWrapper(ctx, config.RecoveryKmsHcl); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	if warningRaw, ok := data.GetOk("auth_token_expiry_warning"); ok {
+		config.AuthTokenExpiryWarning = time.Duration(warningRaw.(int)) * time.Second
+	} else if createOperation {
+		config.AuthTokenExpiryWarning = time.Duration(data.Get("auth_token_expiry_warning").(int)) * time.Second
+	}
+
+	if passwordPolicy, ok := data.GetOk("password_policy"); ok {
+		config.PasswordPolicy = passwordPolicy.(string)
+	}
+
+	rotationPeriod, periodOk := data.GetOk("rotation_period")
+	if periodOk {
+		config.RotationPeriod = time.Duration(rotationPeriod.(int)) * time.Second
+	}
+
+	rotationSchedule, scheduleOk := data.GetOk("rotation_schedule")
+	if scheduleOk {
+		config.RotationSchedule = rotationSchedule.(string)
+	}
+
+	if config.RotationPeriod < 0 {
+		return logical.ErrorResponse("rotation_period cannot be negative"), nil
+	}
+
+	if config.RotationPeriod > 0 && config.RotationSchedule != "" {
+		return logical.ErrorResponse("rotation_period and rotation_schedule are mutually exclusive"), nil
+	}
+
+	if config.RotationSchedule != "" {
+		if _, err := parseRotationSchedule(config.RotationSchedule); err != nil {
+			return logical.ErrorResponse("invalid rotation_schedule: %s", err), nil
+		}
+	}
+
+	if (config.AuthToken != "" || config.RecoveryKmsHcl != "") && (config.RotationPeriod > 0 || config.RotationSchedule != "") {
+		return logical.ErrorResponse("automatic rotation requires password authentication"), nil
+	}
+
+	if caCert, ok := data.GetOk("ca_cert"); ok {
+		config.CACert = caCert.(string)
+	}
+
+	if caPath, ok := data.GetOk("ca_path"); ok {
+		config.CAPath = caPath.(string)
+	}
+
+	if clientCert, ok := data.GetOk("client_cert"); ok {
+		config.ClientCert = clientCert.(string)
+	}
+
+	if clientKey, ok := data.GetOk("client_key"); ok {
+		config.ClientKey = clientKey.(string)
+	}
+
+	if tlsServerName, ok := data.GetOk("tls_server_name"); ok {
+		config.TLSServerName = tlsServerName.(string)
+	}
+
+	if tlsSkipVerify, ok := data.GetOk("tls_skip_verify"); ok {
+		config.TLSSkipVerify = tlsSkipVerify.(bool)
+	}
+
+	if _, err := config.tlsConfig(); err != nil {
+		return logical.ErrorResponse("invalid TLS configuration: %s", err), nil
+	}
+
+	var resp *logical.Response
+	var client *boundaryClient
+
+	// A pre-issued auth token is always read back, so that its expiry
+	// is known, even when the rest of the connection is not verified
+	if data.Get("verify_connection").(bool) {
+		client, err = verifyConnection(ctx, config)
+		if err != nil {
+			return logical.ErrorResponse("error verifying connection to Boundary: %s", err), nil
+		}
+	} else if config.AuthToken != "" {
+		client, err = newClient(config)
+		if err != nil {
+			return logical.ErrorResponse("unable to validate auth_token: %s", err), nil
+		}
+	}
+
+	if client != nil {
+		if warning := config.authTokenWarning(client.tokenExpiration()); warning != "" {
+			resp = &logical.Response{}
+			resp.AddWarning(warning)
+		}
+	}
+
 	entry, err := logical.StorageEntryJSON(configStoragePath, config)
 	if err != nil {
 		return nil, err
@@ -186,14 +518,35 @@ func (b *boundaryBackend) pathConfigWrite(ctx context.Context, req *logical.Requ
 		return nil, err
 	}
 
+	if periodOk || scheduleOk {
+		state, err := getRotationState(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		state.NextRotation, err = config.nextRotation(time.Now())
+		if err != nil {
+			return nil, err
+		}
+		state.Failures = 0
+
+		if err := putRotationState(ctx, req.Storage, state); err != nil {
+			return nil, err
+		}
+	}
+
 	b.reset()
 
-	return nil, nil
+	return resp, nil
 }
 
 func (b *boundaryBackend) pathConfigDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	err := req.Storage.Delete(ctx, configStoragePath)
 
+	if err == nil {
+		err = req.Storage.Delete(ctx, rotationStoragePath)
+	}
+
 	if err == nil {
 		b.reset()
 	}
@@ -210,5 +563,7 @@ The Boundary secret backend requires credentials for managing
 Users, Groups, Grants and Roles.
 You must sign up with a Login name and password and
 specify the Boundary address and Auth-method ID
-before using this secrets backend.
+before using this secrets backend. Alternatively, a
+pre-issued Boundary auth token or the HCL for a recovery
+KMS can be supplied.
 `
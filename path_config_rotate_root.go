@@ -0,0 +1,160 @@
+package boundarysecrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/boundary/api/accounts"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathConfigRotateRoot extends the Vault API with a
+// `/config/rotate-root` endpoint that replaces the
+// password Vault uses to manage Boundary.
+func pathConfigRotateRoot(b *boundaryBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/rotate-root",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                    b.pathConfigRotateRootUpdate,
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+		HelpSynopsis:    pathConfigRotateRootHelpSynopsis,
+		HelpDescription: pathConfigRotateRootHelpDescription,
+	}
+}
+
+func (b *boundaryBackend) pathConfigRotateRootUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := b.rotateRootCredentials(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// rotateRootCredentials changes the password of the configured
+// Boundary account and stores the new value once Vault has
+// confirmed it can log in with it.
+func (b *boundaryBackend) rotateRootCredentials(ctx context.Context, s logical.Storage) error {
+	b.rotationLock.Lock()
+	defer b.rotationLock.Unlock()
+
+	config, err := getConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if config == nil {
+		return errors.New("backend is not configured")
+	}
+
+	if config.AuthToken != "" || config.RecoveryKmsHcl != "" {
+		return errors.New("root credential rotation requires password authentication")
+	}
+
+	client, err := b.getClient(ctx, s)
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	newPassword, err := b.generatePassword(ctx, config.PasswordPolicy)
+	if err != nil {
+		return err
+	}
+
+	accountId, err := findPasswordAccount(ctx, client, config.AuthMethodId, config.LoginName)
+	if err != nil {
+		return err
+	}
+
+	// The new password is recorded before Boundary is changed, so
+	// walRollback can store it if Vault stops before the config is.
+	walId, err := framework.PutWAL(ctx, s, walRootPasswordKind, &walRootPassword{
+		NewPassword: newPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing WAL entry: %w", err)
+	}
+
+	acr := accounts.NewClient(client.Client)
+	_, err = acr.ChangePassword(ctx, accountId, config.Password, newPassword, 0, accounts.WithAutomaticVersioning(true))
+	if err != nil {
+		return discardRejectedWAL(ctx, s, walId, fmt.Errorf("error changing root password: %w", err))
+	}
+
+	newConfig := *config
+	newConfig.Password = newPassword
+
+	if _, err := newClient(&newConfig); err != nil {
+		// Put the old password back so the stored config keeps working
+		_, rerr := acr.SetPassword(ctx, accountId, config.Password, 0, accounts.WithAutomaticVersioning(true))
+		if rerr != nil {
+			return fmt.Errorf("error verifying new root password: %v; error restoring previous password: %w", err, rerr)
+		}
+		return fmt.Errorf("error verifying new root password: %w", err)
+	}
+
+	entry, err := logical.StorageEntryJSON(configStoragePath, &newConfig)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Put(ctx, entry); err != nil {
+		// Put the old password back so the stored config keeps working
+		_, rerr := acr.SetPassword(ctx, accountId, config.Password, 0, accounts.WithAutomaticVersioning(true))
+		if rerr != nil {
+			return fmt.Errorf("error storing new root password: %v; error restoring previous password: %w", err, rerr)
+		}
+		return fmt.Errorf("error storing new root password: %w", err)
+	}
+
+	b.reset()
+
+	if err := framework.DeleteWAL(ctx, s, walId); err != nil {
+		b.Logger().Warn("unable to remove root rotation WAL entry", "error", err)
+	}
+
+	now := time.Now()
+	nextRotation, err := config.nextRotation(now)
+	if err != nil {
+		return err
+	}
+
+	return putRotationState(ctx, s, &rootRotationState{
+		LastRotation: now,
+		NextRotation: nextRotation,
+	})
+}
+
+// findPasswordAccount looks up the ID of the password account
+// with the given login name.
+func findPasswordAccount(ctx context.Context, c *boundaryClient, authMethodId string, loginName string) (string, error) {
+	acr := accounts.NewClient(c.Client)
+
+	filter := filterEquals("/item/attributes/login_name", loginName)
+	alr, err := acr.List(ctx, authMethodId, accounts.WithFilter(filter))
+	if err != nil {
+		return "", fmt.Errorf("error listing accounts: %w", err)
+	}
+
+	if len(alr.Items) != 1 {
+		return "", fmt.Errorf("expected one account with login name %q, found %d", loginName, len(alr.Items))
+	}
+
+	return alr.Items[0].Id, nil
+}
+
+const pathConfigRotateRootHelpSynopsis = `Rotate the password Vault uses to manage Boundary.`
+
+const pathConfigRotateRootHelpDescription = `
+This path generates a new password for the configured
+Boundary account, optionally using the configured Vault
+password policy, and stores it once Vault has verified
+that it can log in with it. The previous password will
+no longer be valid.
+`
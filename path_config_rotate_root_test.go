@@ -0,0 +1,153 @@
+package boundarysecrets
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConfigRotateRoot rotates the root password against
+// a fake Boundary controller.
+func TestConfigRotateRoot(t *testing.T) {
+	b, reqStorage := getTestBackend(t)
+
+	fake := &fakeBoundary{
+		expiration: time.Now().Add(time.Hour),
+		password:   Password,
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	b.System().(*logical.StaticSystemView).SetPasswordPolicy("boundary", func() (string, error) {
+		return "rotated-password", nil
+	})
+
+	err := testConfigCreate(t, b, reqStorage, map[string]interface{}{
+		"login_name":      loginName,
+		"password":        Password,
+		"addr":            srv.URL,
+		"auth_method_id":  authMethodId,
+		"password_policy": "boundary",
+	})
+	require.NoError(t, err)
+
+	t.Run("Rotate Root", func(t *testing.T) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/rotate-root",
+			Storage:   reqStorage,
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		config, err := getConfig(context.Background(), reqStorage)
+		require.NoError(t, err)
+		require.Equal(t, "rotated-password", config.Password)
+		require.Equal(t, "rotated-password", fake.password)
+	})
+
+	t.Run("Rotate Root - missing policy", func(t *testing.T) {
+		err := testConfigUpdate(t, b, reqStorage, map[string]interface{}{
+			"password_policy": "missing",
+		})
+		require.NoError(t, err)
+
+		_, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/rotate-root",
+			Storage:   reqStorage,
+		})
+		require.Error(t, err)
+
+		config, err := getConfig(context.Background(), reqStorage)
+		require.NoError(t, err)
+		require.Equal(t, "rotated-password", config.Password)
+	})
+}
+
+// failingStorage fails every write to one key.
+type failingStorage struct {
+	logical.Storage
+	key string
+}
+
+func (s *failingStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	if entry.Key == s.key {
+		return errors.New("storage unavailable")
+	}
+	return s.Storage.Put(ctx, entry)
+}
+
+// TestConfigRotateRootFailure checks that a failed rotation never
+// leaves Vault without a working root password.
+func TestConfigRotateRootFailure(t *testing.T) {
+	b, reqStorage := getTestBackend(t)
+
+	fake := &fakeBoundary{
+		expiration: time.Now().Add(time.Hour),
+		password:   Password,
+	}
+	configureTestBackend(t, b, reqStorage, fake)
+
+	b.System().(*logical.StaticSystemView).SetPasswordPolicy("boundary", func() (string, error) {
+		return "rotated-password", nil
+	})
+
+	err := testConfigUpdate(t, b, reqStorage, map[string]interface{}{
+		"password_policy": "boundary",
+	})
+	require.NoError(t, err)
+
+	t.Run("Storage Failure", func(t *testing.T) {
+		_, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/rotate-root",
+			Storage:   &failingStorage{Storage: reqStorage, key: configStoragePath},
+		})
+		require.Error(t, err)
+
+		config, err := getConfig(context.Background(), reqStorage)
+		require.NoError(t, err)
+		require.Equal(t, Password, config.Password)
+		require.Equal(t, Password, fake.accountPasswords["acctpw_1234567890"])
+	})
+
+	t.Run("Rollback Stores New Password", func(t *testing.T) {
+		// Boundary has the new password, but Vault stopped before
+		// storing it
+		fake.password = "rotated-password"
+
+		ctx := context.Background()
+		walIds, err := framework.ListWAL(ctx, reqStorage)
+		require.NoError(t, err)
+		require.Len(t, walIds, 1)
+
+		wal, err := framework.GetWAL(ctx, reqStorage, walIds[0])
+		require.NoError(t, err)
+		require.Equal(t, walRootPasswordKind, wal.Kind)
+
+		err = b.walRollback(ctx, &logical.Request{Storage: reqStorage}, wal.Kind, wal.Data)
+		require.NoError(t, err)
+
+		config, err := getConfig(ctx, reqStorage)
+		require.NoError(t, err)
+		require.Equal(t, "rotated-password", config.Password)
+	})
+
+	t.Run("Rollback Keeps Stored Password", func(t *testing.T) {
+		err := b.walRollback(context.Background(), &logical.Request{Storage: reqStorage}, walRootPasswordKind, map[string]interface{}{
+			"new_password": "never-set",
+		})
+		require.NoError(t, err)
+
+		config, err := getConfig(context.Background(), reqStorage)
+		require.NoError(t, err)
+		require.Equal(t, "rotated-password", config.Password)
+	})
+}
@@ -3,10 +3,13 @@ package boundarysecrets
 import (
 	"context"
 	"fmt"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -23,34 +26,56 @@ func TestConfig(t *testing.T) {
 
 	t.Run("Test Configuration", func(t *testing.T) {
 		err := testConfigCreate(t, b, reqStorage, map[string]interface{}{
-			"login_name":     loginName,
-			"password":       Password,
-			"addr":           addr,
-			"auth_method_id": authMethodId,
+			"login_name":        loginName,
+			"password":          Password,
+			"addr":              addr,
+			"auth_method_id":    authMethodId,
+			"verify_connection": false,
 		})
 
 		assert.NoError(t, err)
 
 		err = testConfigRead(t, b, reqStorage, map[string]interface{}{
-			"login_name":     loginName,
-			"auth_method_id": authMethodId,
-			"addr":           addr,
+			"login_name":        loginName,
+			"auth_method_id":    authMethodId,
+			"addr":              addr,
+			"password_policy":   "",
+			"rotation_period":   float64(0),
+			"rotation_schedule": "",
+			"ca_cert":           "",
+			"ca_path":           "",
+			"client_cert":       "",
+			"tls_server_name":   "",
+			"tls_skip_verify":   false,
+
+			"auth_token_expiry_warning": float64(86400),
 		})
 
 		assert.NoError(t, err)
 
 		err = testConfigUpdate(t, b, reqStorage, map[string]interface{}{
-			"login_name":     loginName,
-			"auth_method_id": "ampw_0987654321",
-			"addr":           "http://boundary:9200",
+			"login_name":        loginName,
+			"auth_method_id":    "ampw_0987654321",
+			"addr":              "http://boundary:9200",
+			"verify_connection": false,
 		})
 
 		assert.NoError(t, err)
 
 		err = testConfigRead(t, b, reqStorage, map[string]interface{}{
-			"login_name":     loginName,
-			"auth_method_id": "ampw_0987654321",
-			"addr":           "http://boundary:9200",
+			"login_name":        loginName,
+			"auth_method_id":    "ampw_0987654321",
+			"addr":              "http://boundary:9200",
+			"password_policy":   "",
+			"rotation_period":   float64(0),
+			"rotation_schedule": "",
+			"ca_cert":           "",
+			"ca_path":           "",
+			"client_cert":       "",
+			"tls_server_name":   "",
+			"tls_skip_verify":   false,
+
+			"auth_token_expiry_warning": float64(86400),
 		})
 
 		assert.NoError(t, err)
@@ -59,6 +84,48 @@ func TestConfig(t *testing.T) {
 
 		assert.NoError(t, err)
 	})
+
+	t.Run("Test Configuration - unreachable", func(t *testing.T) {
+		err := testConfigCreate(t, b, reqStorage, map[string]interface{}{
+			"login_name":     loginName,
+			"password":       Password,
+			"addr":           "http://127.0.0.1:1",
+			"auth_method_id": authMethodId,
+		})
+
+		assert.Error(t, err)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      configStoragePath,
+			Storage:   reqStorage,
+		})
+
+		assert.NoError(t, err)
+		assert.Nil(t, resp)
+	})
+
+	t.Run("Test Configuration - invalid TLS", func(t *testing.T) {
+		err := testConfigCreate(t, b, reqStorage, map[string]interface{}{
+			"login_name":     loginName,
+			"password":       Password,
+			"addr":           addr,
+			"auth_method_id": authMethodId,
+			"ca_cert":        "not a certificate",
+		})
+
+		assert.Error(t, err)
+
+		err = testConfigCreate(t, b, reqStorage, map[string]interface{}{
+			"login_name":     loginName,
+			"password":       Password,
+			"addr":           addr,
+			"auth_method_id": authMethodId,
+			"client_key":     "not a key",
+		})
+
+		assert.Error(t, err)
+	})
 }
 
 func testConfigDelete(t *testing.T, b logical.Backend, s logical.Storage) error {
@@ -149,3 +216,130 @@ func testConfigRead(t *testing.T, b logical.Backend, s logical.Storage, expected
 
 	return nil
 }
+
+// TestConfigAuthToken configures the backend with a
+// pre-issued auth token instead of a password login.
+func TestConfigAuthToken(t *testing.T) {
+	b, reqStorage := getTestBackend(t)
+
+	fake := &fakeBoundary{
+		expiration: time.Now().Add(time.Hour),
+		valid:      []string{"at_1234567890_secret"},
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	t.Run("Create With Auth Token", func(t *testing.T) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      configStoragePath,
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"addr":       srv.URL,
+				"auth_token": "at_1234567890_secret",
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		require.False(t, resp.IsError())
+		require.Len(t, resp.Warnings, 1)
+	})
+
+	t.Run("Read Expiry", func(t *testing.T) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      configStoragePath,
+			Storage:   reqStorage,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "at_1234567890", resp.Data["auth_token_id"])
+		require.NotContains(t, resp.Data, "auth_token")
+		require.NotNil(t, resp.Data["auth_token_expiration"])
+		require.Len(t, resp.Warnings, 1)
+	})
+
+	t.Run("No Warning Outside Window", func(t *testing.T) {
+		err := testConfigUpdate(t, b, reqStorage, map[string]interface{}{
+			"auth_token_expiry_warning": "10m",
+		})
+		require.NoError(t, err)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      configStoragePath,
+			Storage:   reqStorage,
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Warnings)
+	})
+
+	t.Run("Reject Invalid Token", func(t *testing.T) {
+		err := testConfigUpdate(t, b, reqStorage, map[string]interface{}{
+			"auth_token": "at_0987654321_wrong",
+		})
+		require.Error(t, err)
+
+		err = testConfigUpdate(t, b, reqStorage, map[string]interface{}{
+			"auth_token": "not-a-token",
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("Validate Token Without Verifying Connection", func(t *testing.T) {
+		err := testConfigUpdate(t, b, reqStorage, map[string]interface{}{
+			"auth_token":        "at_0987654321_wrong",
+			"verify_connection": false,
+		})
+		require.Error(t, err)
+
+		err = testConfigUpdate(t, b, reqStorage, map[string]interface{}{
+			"auth_token":        "at_1234567890_secret",
+			"verify_connection": false,
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Reject Rotation", func(t *testing.T) {
+		err := testConfigUpdate(t, b, reqStorage, map[string]interface{}{
+			"rotation_period": "24h",
+		})
+		require.Error(t, err)
+	})
+}
+
+// TestConfigRecoveryKms configures the backend to authenticate
+// with a recovery KMS instead of a password login.
+func TestConfigRecoveryKms(t *testing.T) {
+	b, reqStorage := getTestBackend(t)
+
+	t.Run("Create With Recovery KMS", func(t *testing.T) {
+		err := testConfigCreate(t, b, reqStorage, map[string]interface{}{
+			"addr":              addr,
+			"recovery_kms_hcl":  testRecoveryKmsHcl,
+			"verify_connection": false,
+		})
+		require.NoError(t, err)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      configStoragePath,
+			Storage:   reqStorage,
+		})
+		require.NoError(t, err)
+		require.NotContains(t, resp.Data, "recovery_kms_hcl")
+	})
+
+	t.Run("Reject Invalid HCL", func(t *testing.T) {
+		err := testConfigUpdate(t, b, reqStorage, map[string]interface{}{
+			"recovery_kms_hcl": `kms "aead" {`,
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("Reject Auth Token", func(t *testing.T) {
+		err := testConfigUpdate(t, b, reqStorage, map[string]interface{}{
+			"auth_token": "at_1234567890_secret",
+		})
+		require.Error(t, err)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -32,6 +33,11 @@ func pathCredentials(b *boundaryBackend) *framework.Path {
 				Description: "Short description of the worker",
 				Required:    false,
 			},
+			"worker_tags": {
+				Type:        framework.TypeMap,
+				Description: "Tags to add to the worker, as a map of keys to lists of values. Must be allowed by the role's allowed_worker_tags.",
+				Required:    false,
+			},
 		},
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ReadOperation:   b.pathCredentialsRead,
@@ -63,12 +69,21 @@ func (b *boundaryBackend) pathCredentialsRead(ctx context.Context, req *logical.
 		return nil, errors.New("error retrieving role: role is nil")
 	}
 
-	return b.createUserCreds(ctx, req, roleEntry, workerName, workerDescription)
+	workerTags, err := parseWorkerTags(d.Get("worker_tags").(map[string]interface{}))
+	if err != nil {
+		return logical.ErrorResponse("invalid worker_tags: %s", err), nil
+	}
+
+	if err := roleEntry.checkWorkerTags(workerTags); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return b.createUserCreds(ctx, req, roleEntry, workerName, workerDescription, workerTags)
 }
 
 // createUserCreds creates a new HashiCups token to store into the Vault backend, generates
 // a response with the secrets information, and checks the TTL and MaxTTL attributes.
-func (b *boundaryBackend) createUserCreds(ctx context.Context, req *logical.Request, role *boundaryRoleEntry, workerName string, workerDescription string) (*logical.Response, error) {
+func (b *boundaryBackend) createUserCreds(ctx context.Context, req *logical.Request, role *boundaryRoleEntry, workerName string, workerDescription string, workerTags map[string][]string) (*logical.Response, error) {
 	var resp *logical.Response
 
 	roleTtl := role.TTL
@@ -79,44 +94,63 @@ func (b *boundaryBackend) createUserCreds(ctx context.Context, req *logical.Requ
 	switch roleType {
 	case "user":
 
-		account, err := b.createAccount(ctx, req.Storage, role)
+		account, err := b.createAccount(ctx, req, role)
 		if err != nil {
-			return nil, err
+			return errorResponse(err)
 		}
 
 		// The response is divided into two objects (1) internal data and (2) data.
 		// If you want to reference any information in your code, you need to
 		// store it in internal data!
-		resp = b.Secret(Account).Response(map[string]interface{}{
-			"account_id":     account.AccountId,
-			"boundary_roles": account.BoundaryRoles,
-			"user_id":        account.UserId,
-			"auth_method_id": account.AuthMethodId,
-			"password":       account.Password,
-			"login_name":     account.LoginName,
-		}, map[string]interface{}{
-			"account_id": account.AccountId,
-			"user_id":    account.UserId,
-			"ttl":        roleTtl,
-			"max_ttl":    roleMaxTtl,
+		respData := map[string]interface{}{
+			"account_id":        account.AccountId,
+			"boundary_roles":    account.BoundaryRoles,
+			"boundary_groups":   account.BoundaryGroups,
+			"user_id":           account.UserId,
+			"auth_method_id":    account.AuthMethodId,
+			"password":          account.Password,
+			"login_name":        account.LoginName,
+			"ephemeral_role_id": account.EphemeralRoleId,
+		}
+
+		// Callers of an auth_token role get a token in place of the password
+		if role.CredentialType == credentialTypeAuthToken {
+			delete(respData, "password")
+			respData["auth_token_id"] = account.AuthTokenId
+			respData["auth_token"] = account.AuthToken
+			respData["auth_token_expiration"] = account.AuthTokenExpiration.Format(time.RFC3339)
+		}
+
+		resp = b.Secret(Account).Response(respData, map[string]interface{}{
+			"account_id":        account.AccountId,
+			"user_id":           account.UserId,
+			"ephemeral_role_id": account.EphemeralRoleId,
+			"boundary_groups":   account.BoundaryGroups,
+			"auth_token_id":     account.AuthTokenId,
+			"ttl":               roleTtl.Seconds(),
+			"max_ttl":           roleMaxTtl.Seconds(),
 		})
 	case "worker":
-		worker, err := b.createWorker(ctx, req.Storage, role, workerName, workerDescription)
+		tags := mergeWorkerTags(role.WorkerTags, workerTags)
+
+		worker, err := b.createWorker(ctx, req, role, workerName, workerDescription, tags)
 		if err != nil {
-			return logical.ErrorResponse("unable to create worker, error:", err), nil
-			//return nil, err
+			return errorResponse(err)
 		}
 
-		resp = b.Secret(Worker).Response(map[string]interface{}{
-			"worker_id":        worker.WorkerId,
-			"worker_name":      worker.WorkerName,
-			"activation_token": worker.ActivationToken,
-		}, map[string]interface{}{
-			"worker_id":   worker.WorkerId,
-			"worker_name": worker.WorkerName,
-			"ttl":         roleTtl,
-			"max_ttl":     roleMaxTtl,
-		})
+		if err := b.trackWorkerActivation(ctx, req.Storage, role, worker.WorkerId); err != nil {
+			return nil, err
+		}
+
+		resp = b.workerResponse(role, worker)
+		resp.Data["activation_token"] = worker.ActivationToken
+	case "scope":
+		scope, err := b.createScope(ctx, req, role)
+		if err != nil {
+			return errorResponse(err)
+		}
+
+		resp = b.scopeResponse(role, scope)
 
 	}
 
@@ -132,17 +166,43 @@ func (b *boundaryBackend) createUserCreds(ctx context.Context, req *logical.Requ
 }
 
 // createAccount uses the Boundary client to create a new account
-func (b *boundaryBackend) createAccount(ctx context.Context, s logical.Storage, roleEntry *boundaryRoleEntry) (*boundaryAccount, error) {
-	client, err := b.getClient(ctx, s)
+func (b *boundaryBackend) createAccount(ctx context.Context, req *logical.Request, roleEntry *boundaryRoleEntry) (*boundaryAccount, error) {
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	templateData, err := b.newNameTemplateData(req, roleEntry.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	loginName, userName, err := roleEntry.accountNames(templateData)
+	if err != nil {
+		return nil, newUserError(err)
+	}
+
+	passwordPolicy := roleEntry.PasswordPolicy
+	if passwordPolicy == "" {
+		config, err := getConfig(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if config != nil {
+			passwordPolicy = config.PasswordPolicy
+		}
+	}
+
+	accountPassword, err := b.generatePassword(ctx, passwordPolicy)
 	if err != nil {
 		return nil, err
 	}
 
 	var token *boundaryAccount
 
-	token, err = createAccount(ctx, client, roleEntry.Name, roleEntry.AuthMethodID, roleEntry.BoundaryRoles, roleEntry.ScopeId)
+	token, err = createAccount(ctx, req.Storage, client, roleEntry, loginName, userName, accountPassword)
 	if err != nil {
-		return nil, fmt.Errorf("error creating Boundary Account: %w", err)
+		return nil, classifyError(fmt.Errorf("error creating Boundary Account: %w", err))
 	}
 
 	if token == nil {
@@ -153,17 +213,22 @@ func (b *boundaryBackend) createAccount(ctx context.Context, s logical.Storage,
 
 }
 
-func (b *boundaryBackend) createWorker(ctx context.Context, s logical.Storage, roleEntry *boundaryRoleEntry, workerName string, description string) (*boundaryWorker, error) {
-	client, err := b.getClient(ctx, s)
+func (b *boundaryBackend) createWorker(ctx context.Context, req *logical.Request, roleEntry *boundaryRoleEntry, workerName string, description string, tags map[string][]string) (*boundaryWorker, error) {
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	workerName, err = b.workerName(ctx, req, client, roleEntry, workerName)
 	if err != nil {
 		return nil, err
 	}
 
 	var worker *boundaryWorker
 
-	worker, err = createWorker(ctx, client, roleEntry.ScopeId, workerName, description)
+	worker, err = createWorker(ctx, client, roleEntry.ScopeId, workerName, description, tags)
 	if err != nil {
-		return nil, fmt.Errorf("error creating Boundary worker auth token: %w", err)
+		return nil, classifyError(fmt.Errorf("error creating Boundary worker auth token: %w", err))
 	}
 
 	if worker == nil {
@@ -174,8 +239,67 @@ func (b *boundaryBackend) createWorker(ctx context.Context, s logical.Storage, r
 
 }
 
+// maxWorkerNameAttempts is how many names are generated from a
+// role's template before giving up on finding an unused one.
+const maxWorkerNameAttempts = 5
+
+// workerName returns the name for a new worker in the role's scope.
+// This is the requested name if there is one, and otherwise a name
+// generated from the role's template. The name must match the role's
+// allowed patterns and not already be taken by another worker.
+func (b *boundaryBackend) workerName(ctx context.Context, req *logical.Request, c *boundaryClient, roleEntry *boundaryRoleEntry, requested string) (string, error) {
+	templateData, err := b.newNameTemplateData(req, roleEntry.Name)
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 0; attempt < maxWorkerNameAttempts; attempt++ {
+		name := requested
+		if name == "" {
+			name, err = roleEntry.generateWorkerName(templateData)
+			if err != nil {
+				return "", newUserError(err)
+			}
+		}
+
+		if err := roleEntry.checkWorkerName(name); err != nil {
+			return "", newUserError(err)
+		}
+
+		inUse, err := workerNameInUse(ctx, c, roleEntry.ScopeId, name)
+		if err != nil {
+			return "", classifyError(fmt.Errorf("error checking worker name: %w", err))
+		}
+
+		if !inUse {
+			return name, nil
+		}
+
+		if requested != "" {
+			return "", newUserError(fmt.Errorf("a worker named %q already exists in scope %q", name, roleEntry.ScopeId))
+		}
+	}
+
+	return "", newUserError(fmt.Errorf("unable to generate an unused worker name in %d attempts", maxWorkerNameAttempts))
+}
+
+// workerResponse returns a worker lease for a worker created from
+// the role.
+func (b *boundaryBackend) workerResponse(role *boundaryRoleEntry, worker *boundaryWorker) *logical.Response {
+	return b.Secret(Worker).Response(map[string]interface{}{
+		"worker_id":   worker.WorkerId,
+		"worker_name": worker.WorkerName,
+		"worker_tags": worker.WorkerTags,
+	}, map[string]interface{}{
+		"worker_id":   worker.WorkerId,
+		"worker_name": worker.WorkerName,
+		"ttl":         role.TTL.Seconds(),
+		"max_ttl":     role.MaxTTL.Seconds(),
+	})
+}
+
 const pathCredentialsHelpSyn = `
-Generate a Boundary account or worker from a specific Vault role.
+Generate a Boundary account, worker or scope from a specific Vault role.
 `
 
 const pathCredentialsHelpDesc = `
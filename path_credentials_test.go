@@ -9,6 +9,7 @@ import (
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/helper/logging"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
 )
 
 // newAcceptanceTestEnv creates a test environment for credentials
@@ -56,3 +57,128 @@ func TestAcceptanceUserToken(t *testing.T) {
 	t.Run("read user token cred", acceptanceTestEnv.ReadUserToken)
 	t.Run("cleanup user tokens", acceptanceTestEnv.CleanupUserTokens)
 }
+
+// TestGrantStringsCreds checks that a role with grant strings gets
+// an ephemeral Boundary role for each set of credentials, and that
+// revoking the lease deletes it.
+func TestGrantStringsCreds(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	fake := &fakeBoundary{expiration: time.Now().Add(time.Hour)}
+	configureTestBackend(t, b, s, fake)
+
+	_, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+		"scope_id":       scope_id,
+		"auth_method_id": auth_method_id,
+		"role_type":      roleType,
+		"ttl":            testTTL,
+		"grant_strings":  "id=*;type=target;actions=list,authorize-session",
+		"grant_scope_id": "p_1234567890",
+	})
+	require.NoError(t, err)
+
+	var secret *logical.Secret
+
+	t.Run("Create Ephemeral Role", func(t *testing.T) {
+		resp, err := testCredsRead(t, b, s, roleName)
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+
+		require.Equal(t, "r_0987654321", resp.Secret.InternalData["ephemeral_role_id"])
+		require.Equal(t, []string{"id=*;type=target;actions=list,authorize-session"}, fake.roleGrants["r_0987654321"])
+
+		secret = resp.Secret
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		require.NotNil(t, secret)
+		fake.deleted = nil
+
+		_, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RevokeOperation,
+			Secret:    secret,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"/v1/roles/r_0987654321",
+			"/v1/users/u_1234567890",
+			"/v1/accounts/acctpw_0987654321",
+		}, fake.deleted)
+	})
+}
+
+// TestAuthTokenCreds checks that a role with credential_type
+// auth_token returns a token for the new account, and that revoking
+// the lease deletes the token, the user and the account.
+func TestAuthTokenCreds(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	fake := &fakeBoundary{expiration: time.Now().Add(time.Hour)}
+	configureTestBackend(t, b, s, fake)
+
+	_, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+		"boundary_roles":  boundary_roles,
+		"scope_id":        scope_id,
+		"auth_method_id":  auth_method_id,
+		"role_type":       roleType,
+		"ttl":             testTTL,
+		"credential_type": "auth_token",
+	})
+	require.NoError(t, err)
+
+	var secret *logical.Secret
+
+	t.Run("Issue Auth Token", func(t *testing.T) {
+		resp, err := testCredsRead(t, b, s, roleName)
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+
+		// Vault's own login was the first token the fake issued
+		require.Equal(t, "at_2", resp.Data["auth_token_id"])
+		require.Equal(t, "at_token2", resp.Data["auth_token"])
+		require.NotEmpty(t, resp.Data["auth_token_expiration"])
+		require.NotContains(t, resp.Data, "password")
+		require.Equal(t, "at_2", resp.Secret.InternalData["auth_token_id"])
+
+		secret = resp.Secret
+	})
+
+	t.Run("Renew", func(t *testing.T) {
+		require.NotNil(t, secret)
+
+		renew, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RenewOperation,
+			Secret:    secret,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, time.Duration(testTTL)*time.Second, renew.Secret.TTL)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		require.NotNil(t, secret)
+		fake.deleted = nil
+
+		_, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RevokeOperation,
+			Secret:    secret,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"/v1/auth-tokens/at_2",
+			"/v1/users/u_1234567890",
+			"/v1/accounts/acctpw_0987654321",
+		}, fake.deleted)
+	})
+}
+
+func testCredsRead(t *testing.T, b *boundaryBackend, s logical.Storage, name string) (*logical.Response, error) {
+	t.Helper()
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/" + name,
+		Storage:   s,
+	})
+}
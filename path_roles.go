@@ -3,30 +3,86 @@ package boundarysecrets
 import (
 	"context"
 	"fmt"
+	"github.com/hashicorp/boundary/api/groups"
+	"github.com/hashicorp/boundary/api/roles"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 	"time"
 )
 
 type boundaryRoleEntry struct {
-	AuthMethodID  string        `json:"auth_method_id"`
-	Name          string        `json:"name"`
-	ScopeId       string        `json:"scope_id"`
-	BoundaryRoles string        `json:"boundary_roles"`
-	TTL           time.Duration `json:"ttl"`
-	MaxTTL        time.Duration `json:"max_ttl"`
-	RoleType      string        `json:"role_type"`
+	AuthMethodID   string        `json:"auth_method_id"`
+	Name           string        `json:"name"`
+	ScopeId        string        `json:"scope_id"`
+	BoundaryRoles  []string      `json:"boundary_roles"`
+	BoundaryGroups []string      `json:"boundary_groups"`
+	TTL            time.Duration `json:"ttl"`
+	MaxTTL         time.Duration `json:"max_ttl"`
+	RoleType       string        `json:"role_type"`
+	GrantStrings   []string      `json:"grant_strings"`
+	GrantScopeId   string        `json:"grant_scope_id"`
+
+	// CredentialType is either userpass or auth_token. Roles
+	// stored before it was added are treated as userpass.
+	CredentialType string `json:"credential_type"`
+
+	// AllowedTargetIds are the targets authorize-session may
+	// broker sessions to for this role.
+	AllowedTargetIds []string `json:"allowed_target_ids"`
+
+	UsernameTemplate    string `json:"username_template"`
+	DisplayNameTemplate string `json:"display_name_template"`
+
+	// PasswordPolicy overrides the config's password_policy
+	// for accounts generated from this role.
+	PasswordPolicy string `json:"password_policy"`
+
+	// WorkerTags are applied to every worker generated from the
+	// role. Callers may add their own tags to a worker, but only
+	// keys and values listed in AllowedWorkerTags.
+	WorkerTags        map[string][]string `json:"worker_tags"`
+	AllowedWorkerTags map[string][]string `json:"allowed_worker_tags"`
+
+	// WorkerNameTemplate names workers when the caller does not,
+	// and every worker name must match AllowedWorkerNamePatterns.
+	WorkerNameTemplate        string   `json:"worker_name_template"`
+	AllowedWorkerNamePatterns []string `json:"allowed_worker_name_patterns"`
+
+	// ActivationTimeout is how long a worker has to use its
+	// activation token before Vault deletes it. Zero disables it.
+	ActivationTimeout time.Duration `json:"activation_timeout"`
+
+	// ScopeNameTemplate names the scopes created by scope roles,
+	// and BootstrapAdmin creates an admin user for each of them.
+	ScopeNameTemplate string `json:"scope_name_template"`
+	BootstrapAdmin    bool   `json:"bootstrap_admin"`
 }
 
 func (r *boundaryRoleEntry) toResponseData() map[string]interface{} {
 	respData := map[string]interface{}{
-		"ttl":            r.TTL.Seconds(),
-		"max_ttl":        r.MaxTTL.Seconds(),
-		"boundary_roles": r.BoundaryRoles,
-		"name":           r.Name,
-		"auth_method_id": r.AuthMethodID,
-		"scope_id":       r.ScopeId,
-		"role_type":      r.RoleType,
+		"ttl":                          r.TTL.Seconds(),
+		"max_ttl":                      r.MaxTTL.Seconds(),
+		"boundary_roles":               r.BoundaryRoles,
+		"boundary_groups":              r.BoundaryGroups,
+		"name":                         r.Name,
+		"auth_method_id":               r.AuthMethodID,
+		"scope_id":                     r.ScopeId,
+		"role_type":                    r.RoleType,
+		"grant_strings":                r.GrantStrings,
+		"grant_scope_id":               r.GrantScopeId,
+		"credential_type":              r.CredentialType,
+		"allowed_target_ids":           r.AllowedTargetIds,
+		"username_template":            r.UsernameTemplate,
+		"display_name_template":        r.DisplayNameTemplate,
+		"password_policy":              r.PasswordPolicy,
+		"worker_tags":                  r.WorkerTags,
+		"allowed_worker_tags":          r.AllowedWorkerTags,
+		"worker_name_template":         r.WorkerNameTemplate,
+		"allowed_worker_name_patterns": r.AllowedWorkerNamePatterns,
+		"activation_timeout":           r.ActivationTimeout.Seconds(),
+		"scope_name_template":          r.ScopeNameTemplate,
+		"bootstrap_admin":              r.BootstrapAdmin,
 	}
 	return respData
 }
@@ -42,10 +98,15 @@ func pathRole(b *boundaryBackend) []*framework.Path {
 					Required:    true,
 				},
 				"boundary_roles": {
-					Type:        framework.TypeString, // This should be a list of Boundary roles
+					Type:        framework.TypeCommaStringSlice,
 					Description: "List of Boundary roles to be assigned to generated users.",
 					Required:    false,
 				},
+				"boundary_groups": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "List of Boundary groups that generated users are added to.",
+					Required:    false,
+				},
 				"ttl": {
 					Type:        framework.TypeDurationSecond,
 					Description: "Default lease for generated credentials. If not set or set to 0, will use system default.",
@@ -64,9 +125,79 @@ func pathRole(b *boundaryBackend) []*framework.Path {
 				},
 				"role_type": {
 					Type:        framework.TypeLowerCaseString,
-					Description: "Must be either `user` or `worker` type",
+					Description: "Must be either `user`, `worker` or `scope` type",
 					Required:    true,
 				},
+				"grant_strings": {
+					Type:        framework.TypeStringSlice, // Grants contain commas, so this cannot be a comma separated string
+					Description: "Grant strings for a Boundary role created for each generated user and deleted when the lease is revoked.",
+					Required:    false,
+				},
+				"grant_scope_id": {
+					Type:        framework.TypeString,
+					Description: "Scope ID that the grants of the generated Boundary role apply to. Defaults to scope_id.",
+					Required:    false,
+				},
+				"credential_type": {
+					Type:        framework.TypeLowerCaseString,
+					Description: "Credentials returned for user roles. Must be either `userpass` or `auth_token`. Defaults to `userpass`.",
+					Required:    false,
+				},
+				"allowed_target_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Boundary target IDs that sessions may be authorized for through the authorize-session endpoint.",
+					Required:    false,
+				},
+				"username_template": {
+					Type:        framework.TypeString,
+					Description: "Template for the login name of generated accounts.",
+					Required:    false,
+				},
+				"display_name_template": {
+					Type:        framework.TypeString,
+					Description: "Template for the name of generated users and accounts. Must render a unique name for each request. Defaults to the login name.",
+					Required:    false,
+				},
+				"password_policy": {
+					Type:        framework.TypeString,
+					Description: "The Vault password policy used to generate account passwords. Defaults to the password_policy in config.",
+					Required:    false,
+				},
+				"worker_tags": {
+					Type:        framework.TypeMap,
+					Description: "Tags applied to generated workers, as a map of keys to lists of values.",
+					Required:    false,
+				},
+				"allowed_worker_tags": {
+					Type:        framework.TypeMap,
+					Description: "Tag keys and values callers may add to generated workers. A value of `*` allows any value for a key.",
+					Required:    false,
+				},
+				"worker_name_template": {
+					Type:        framework.TypeString,
+					Description: "Template for the name of generated workers when the caller does not give one.",
+					Required:    false,
+				},
+				"allowed_worker_name_patterns": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Glob patterns that worker names must match. Any name is allowed if empty.",
+					Required:    false,
+				},
+				"activation_timeout": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How long a generated worker has to activate before it is deleted. The worker's lease is not revoked when this happens; it stays until it expires or is revoked, but can no longer be renewed. If not set or set to 0, workers are kept until the lease ends.",
+					Required:    false,
+				},
+				"scope_name_template": {
+					Type:        framework.TypeString,
+					Description: "Template for the name of scopes created by scope roles.",
+					Required:    false,
+				},
+				"bootstrap_admin": {
+					Type:        framework.TypeBool,
+					Description: "Whether scope roles create an admin user for each scope, with an account in auth_method_id.",
+					Required:    false,
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.ReadOperation: &framework.PathOperation{
@@ -98,6 +229,11 @@ func pathRole(b *boundaryBackend) []*framework.Path {
 	}
 }
 
+const (
+	credentialTypeUserPass  = "userpass"
+	credentialTypeAuthToken = "auth_token"
+)
+
 const (
 	pathRoleHelpSynopsis    = `Manages the Vault role for generating Boundary users.`
 	pathRoleHelpDescription = `
@@ -124,7 +260,18 @@ func (b *boundaryBackend) getRole(ctx context.Context, s logical.Storage, name s
 	var role boundaryRoleEntry
 
 	if err := entry.DecodeJSON(&role); err != nil {
-		return nil, err
+		// Roles written before boundary_roles became a list stored it
+		// as a comma separated string, so convert it on the way out
+		var legacy struct {
+			boundaryRoleEntry
+			BoundaryRoles string `json:"boundary_roles"`
+		}
+		if lerr := entry.DecodeJSON(&legacy); lerr != nil {
+			return nil, err
+		}
+
+		role = legacy.boundaryRoleEntry
+		role.BoundaryRoles = strutil.RemoveDuplicatesStable(strutil.ParseStringSlice(legacy.BoundaryRoles, ","), false)
 	}
 	return &role, nil
 }
@@ -190,22 +337,165 @@ func (b *boundaryBackend) pathRolesWrite(ctx context.Context, req *logical.Reque
 		roleType = rt.(string)
 		roleEntry.RoleType = roleType
 	} else if !ok && createOperation {
-		return nil, fmt.Errorf("missing role type. must be either `user` or `worker`")
+		return nil, fmt.Errorf("missing role type. must be either `user`, `worker` or `scope`")
+	}
+
+	if roleType != "user" && roleType != "worker" && roleType != "scope" {
+		return logical.ErrorResponse("must be set to either `user`, `worker` or `scope`"), nil
+	}
+
+	// Check there is a list of boundary roles, and that each one exists
+	if boundaryRoles, ok := d.GetOk("boundary_roles"); ok {
+		roleEntry.BoundaryRoles = strutil.RemoveDuplicatesStable(boundaryRoles.([]string), false)
+
+		if len(roleEntry.BoundaryRoles) > 0 {
+			client, err := b.getClient(ctx, req.Storage)
+			if err != nil {
+				return nil, fmt.Errorf("error getting client: %w", err)
+			}
+
+			// Only IDs Boundary rejects are reported as invalid, not
+			// a controller that could not be reached
+			if err := validateBoundaryRoles(ctx, client, roleEntry.BoundaryRoles); err != nil {
+				return errorResponse(classifyError(err))
+			}
+		}
+	}
+
+	// Check that each boundary group exists
+	if boundaryGroups, ok := d.GetOk("boundary_groups"); ok {
+		roleEntry.BoundaryGroups = strutil.RemoveDuplicatesStable(boundaryGroups.([]string), false)
+
+		if len(roleEntry.BoundaryGroups) > 0 {
+			client, err := b.getClient(ctx, req.Storage)
+			if err != nil {
+				return nil, fmt.Errorf("error getting client: %w", err)
+			}
+
+			if err := validateBoundaryGroups(ctx, client, roleEntry.BoundaryGroups); err != nil {
+				return errorResponse(classifyError(err))
+			}
+		}
 	}
 
-	if roleType != "user" && roleType != "worker" {
-		return logical.ErrorResponse("must be set to either `user` or `worker`"), nil
+	if grantStrings, ok := d.GetOk("grant_strings"); ok {
+		roleEntry.GrantStrings = grantStrings.([]string)
 	}
 
-	var boundaryRoles interface{}
+	if grantScopeId, ok := d.GetOk("grant_scope_id"); ok {
+		roleEntry.GrantScopeId = grantScopeId.(string)
+	}
+
+	if roleType == "user" && len(roleEntry.BoundaryRoles) == 0 && len(roleEntry.BoundaryGroups) == 0 && len(roleEntry.GrantStrings) == 0 {
+		return nil, fmt.Errorf("missing boundary_roles, boundary_groups or grant_strings in role")
+	}
+
+	if credentialType, ok := d.GetOk("credential_type"); ok {
+		roleEntry.CredentialType = credentialType.(string)
+	} else if createOperation && roleType == "user" {
+		roleEntry.CredentialType = credentialTypeUserPass
+	}
+
+	if roleEntry.CredentialType != "" && roleEntry.CredentialType != credentialTypeUserPass && roleEntry.CredentialType != credentialTypeAuthToken {
+		return logical.ErrorResponse("credential_type must be set to either `userpass` or `auth_token`"), nil
+	}
+
+	if allowedTargetIds, ok := d.GetOk("allowed_target_ids"); ok {
+		roleEntry.AllowedTargetIds = allowedTargetIds.([]string)
+	}
+
+	if usernameTemplate, ok := d.GetOk("username_template"); ok {
+		roleEntry.UsernameTemplate = usernameTemplate.(string)
+	}
+
+	if displayNameTemplate, ok := d.GetOk("display_name_template"); ok {
+		roleEntry.DisplayNameTemplate = displayNameTemplate.(string)
+	}
+
+	if roleEntry.UsernameTemplate != "" || roleEntry.DisplayNameTemplate != "" {
+		// Render the templates with sample data to catch syntax errors
+		// and login names Boundary would reject
+		sample := &nameTemplateData{
+			RoleName:    roleEntry.Name,
+			DisplayName: "token",
+			EntityID:    "00000000-0000-0000-0000-000000000000",
+			Metadata:    map[string]string{},
+		}
+		if _, _, err := roleEntry.accountNames(sample); err != nil {
+			return logical.ErrorResponse("invalid template: %s", err), nil
+		}
+
+		if roleEntry.DisplayNameTemplate != "" {
+			if err := roleEntry.checkDisplayNameUnique(sample); err != nil {
+				return logical.ErrorResponse("invalid template: %s", err), nil
+			}
+		}
+	}
+
+	if passwordPolicy, ok := d.GetOk("password_policy"); ok {
+		roleEntry.PasswordPolicy = passwordPolicy.(string)
+
+		// Vault does not say why a policy failed, so try it now to
+		// report a missing policy here rather than on every request
+		if roleEntry.PasswordPolicy != "" {
+			if _, err := b.generatePassword(ctx, roleEntry.PasswordPolicy); err != nil {
+				return logical.ErrorResponse("password policy %q could not be used, check that it exists: %s", roleEntry.PasswordPolicy, err), nil
+			}
+		}
+	}
+
+	if workerTags, ok := d.GetOk("worker_tags"); ok {
+		roleEntry.WorkerTags, err = parseWorkerTags(workerTags.(map[string]interface{}))
+		if err != nil {
+			return logical.ErrorResponse("invalid worker_tags: %s", err), nil
+		}
+	}
+
+	if allowedWorkerTags, ok := d.GetOk("allowed_worker_tags"); ok {
+		roleEntry.AllowedWorkerTags, err = parseWorkerTags(allowedWorkerTags.(map[string]interface{}))
+		if err != nil {
+			return logical.ErrorResponse("invalid allowed_worker_tags: %s", err), nil
+		}
+	}
 
-	// Check there is a list of boundary roles
-	if boundaryRoles, ok = d.GetOk("boundary_roles"); ok {
-		roleEntry.BoundaryRoles = boundaryRoles.(string)
+	if workerNameTemplate, ok := d.GetOk("worker_name_template"); ok {
+		roleEntry.WorkerNameTemplate = workerNameTemplate.(string)
+
+		if roleEntry.WorkerNameTemplate != "" {
+			_, err := roleEntry.generateWorkerName(&nameTemplateData{
+				RoleName:    roleEntry.Name,
+				DisplayName: "token",
+				EntityID:    "00000000-0000-0000-0000-000000000000",
+				Metadata:    map[string]string{},
+			})
+			if err != nil {
+				return logical.ErrorResponse("invalid template: %s", err), nil
+			}
+		}
 	}
 
-	if roleType == "user" && boundaryRoles == nil {
-		return nil, fmt.Errorf("missing boundary_roles in role")
+	if allowedWorkerNamePatterns, ok := d.GetOk("allowed_worker_name_patterns"); ok {
+		roleEntry.AllowedWorkerNamePatterns = allowedWorkerNamePatterns.([]string)
+	}
+
+	if scopeNameTemplate, ok := d.GetOk("scope_name_template"); ok {
+		roleEntry.ScopeNameTemplate = scopeNameTemplate.(string)
+
+		if roleEntry.ScopeNameTemplate != "" {
+			_, err := renderNameTemplate(roleEntry.ScopeNameTemplate, &nameTemplateData{
+				RoleName:    roleEntry.Name,
+				DisplayName: "token",
+				EntityID:    "00000000-0000-0000-0000-000000000000",
+				Metadata:    map[string]string{},
+			})
+			if err != nil {
+				return logical.ErrorResponse("invalid template: %s", err), nil
+			}
+		}
+	}
+
+	if bootstrapAdmin, ok := d.GetOk("bootstrap_admin"); ok {
+		roleEntry.BootstrapAdmin = bootstrapAdmin.(bool)
 	}
 
 	// Check there is an auth method id for user role
@@ -219,6 +509,10 @@ func (b *boundaryBackend) pathRolesWrite(ctx context.Context, req *logical.Reque
 		return nil, fmt.Errorf("missing auth_method_id in role")
 	}
 
+	if roleType == "scope" && roleEntry.BootstrapAdmin && roleEntry.AuthMethodID == "" {
+		return logical.ErrorResponse("bootstrap_admin requires auth_method_id in role"), nil
+	}
+
 	// Check there is a scope id
 	if scopeId, ok := d.GetOk("scope_id"); ok {
 		roleEntry.ScopeId = scopeId.(string)
@@ -242,6 +536,14 @@ func (b *boundaryBackend) pathRolesWrite(ctx context.Context, req *logical.Reque
 		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
 	}
 
+	if activationTimeout, ok := d.GetOk("activation_timeout"); ok {
+		roleEntry.ActivationTimeout = time.Duration(activationTimeout.(int)) * time.Second
+	}
+
+	if roleEntry.ActivationTimeout < 0 {
+		return logical.ErrorResponse("activation_timeout cannot be negative"), nil
+	}
+
 	if err := setRole(ctx, req.Storage, name.(string), roleEntry); err != nil {
 		return nil, err
 	}
@@ -249,6 +551,34 @@ func (b *boundaryBackend) pathRolesWrite(ctx context.Context, req *logical.Reque
 	return nil, nil
 }
 
+// validateBoundaryRoles checks that every role ID refers to a
+// Boundary role that Vault can read.
+func validateBoundaryRoles(ctx context.Context, c *boundaryClient, roleIds []string) error {
+	rcr := roles.NewClient(c.Client)
+
+	for _, roleId := range roleIds {
+		if _, err := rcr.Read(ctx, roleId); err != nil {
+			return fmt.Errorf("unable to read boundary role %q: %w", roleId, err)
+		}
+	}
+
+	return nil
+}
+
+// validateBoundaryGroups checks that every group ID refers to a
+// Boundary group that Vault can read.
+func validateBoundaryGroups(ctx context.Context, c *boundaryClient, groupIds []string) error {
+	gcr := groups.NewClient(c.Client)
+
+	for _, groupId := range groupIds {
+		if _, err := gcr.Read(ctx, groupId); err != nil {
+			return fmt.Errorf("unable to read boundary group %q: %w", groupId, err)
+		}
+	}
+
+	return nil
+}
+
 func (b *boundaryBackend) pathRolesDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	err := req.Storage.Delete(ctx, "role/"+d.Get("name").(string))
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/require"
@@ -13,6 +14,7 @@ const (
 	roleName        = "testboundary"
 	auth_method_id  = "ampw_1234567890"
 	boundary_roles  = "r_cbvEFZbN1S,r_r8mxdp7zOp"
+	missing_role    = "r_0000000000"
 	scope_id        = "global"
 	credential_type = "userpass"
 	testTTL         = int64(120)
@@ -25,6 +27,11 @@ const (
 // role create, read, update, and delete.
 func TestUserRole(t *testing.T) {
 	b, s := getTestBackend(t)
+	fake := &fakeBoundary{
+		expiration: time.Now().Add(time.Hour),
+		roles:      []string{"r_cbvEFZbN1S", "r_r8mxdp7zOp", "r_bauDEYaM2R"},
+	}
+	configureTestBackend(t, b, s, fake)
 
 	t.Run("List All Roles", func(t *testing.T) {
 		for i := 1; i <= 10; i++ {
@@ -70,7 +77,7 @@ func TestUserRole(t *testing.T) {
 		require.Nil(t, err)
 		require.Nil(t, resp.Error())
 		require.NotNil(t, resp)
-		require.Equal(t, resp.Data["boundary_roles"], boundary_roles)
+		require.Equal(t, resp.Data["boundary_roles"], []string{"r_cbvEFZbN1S", "r_r8mxdp7zOp"})
 		require.Equal(t, resp.Data["auth_method_id"], auth_method_id)
 		require.Equal(t, resp.Data["scope_id"], scope_id)
 		//require.Equal(t, resp.Data["credential_type"], credential_type)
@@ -96,13 +103,78 @@ func TestUserRole(t *testing.T) {
 		require.Nil(t, err)
 		require.Nil(t, resp.Error())
 		require.NotNil(t, resp)
-		require.Equal(t, resp.Data["boundary_roles"], "r_bauDEYaM2R")
+		require.Equal(t, resp.Data["boundary_roles"], []string{"r_bauDEYaM2R"})
 		require.Equal(t, resp.Data["scope_id"], "0_1234567890")
 		require.Equal(t, resp.Data["auth_method_id"], "ampw_0987654321")
 		require.Equal(t, resp.Data["ttl"], float64(60))
 		require.Equal(t, resp.Data["max_ttl"], float64(18000))
 	})
 
+	t.Run("Update User Role - whitespace", func(t *testing.T) {
+		resp, err := testTokenRoleUpdate(t, b, s, map[string]interface{}{
+			"boundary_roles": " r_cbvEFZbN1S, r_r8mxdp7zOp,,",
+			"role_type":      roleType,
+		})
+
+		require.Nil(t, err)
+		require.Nil(t, resp)
+
+		resp, err = testTokenRoleRead(t, b, s)
+		require.Nil(t, err)
+		require.Equal(t, []string{"r_cbvEFZbN1S", "r_r8mxdp7zOp"}, resp.Data["boundary_roles"])
+	})
+
+	t.Run("Update User Role - missing Boundary role", func(t *testing.T) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "role/" + roleName,
+			Data: map[string]interface{}{
+				"boundary_roles": missing_role,
+				"role_type":      roleType,
+			},
+			Storage: s,
+		})
+
+		require.Nil(t, err)
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), missing_role)
+	})
+
+	t.Run("Update User Role - Boundary unavailable", func(t *testing.T) {
+		fake.unavailable = []string{"/v1/roles/" + missing_role}
+		defer func() { fake.unavailable = nil }()
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "role/" + roleName,
+			Data: map[string]interface{}{
+				"boundary_roles": missing_role,
+				"role_type":      roleType,
+			},
+			Storage: s,
+		})
+
+		require.Error(t, err)
+		require.False(t, resp != nil && resp.IsError())
+	})
+
+	t.Run("Read Legacy User Role", func(t *testing.T) {
+		entry, err := logical.StorageEntryJSON("role/legacy", map[string]interface{}{
+			"name":           "legacy",
+			"boundary_roles": "r_cbvEFZbN1S, r_r8mxdp7zOp,",
+			"scope_id":       scope_id,
+			"auth_method_id": auth_method_id,
+			"role_type":      roleType,
+		})
+		require.NoError(t, err)
+		require.NoError(t, s.Put(context.Background(), entry))
+
+		role, err := b.getRole(context.Background(), s, "legacy")
+		require.NoError(t, err)
+		require.Equal(t, []string{"r_cbvEFZbN1S", "r_r8mxdp7zOp"}, role.BoundaryRoles)
+		require.Equal(t, auth_method_id, role.AuthMethodID)
+	})
+
 	t.Run("Delete User Role", func(t *testing.T) {
 		_, err := testTokenRoleDelete(t, b, s)
 
@@ -110,6 +182,243 @@ func TestUserRole(t *testing.T) {
 	})
 }
 
+// TestUserRoleGrantStrings checks that a user role can carry
+// inline grants in place of existing Boundary roles.
+func TestUserRoleGrantStrings(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	grants := []string{"id=*;type=target;actions=list,authorize-session"}
+
+	t.Run("Create User Role - pass", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+			"grant_strings":  grants,
+			"grant_scope_id": "p_1234567890",
+			"scope_id":       scope_id,
+			"auth_method_id": auth_method_id,
+			"role_type":      roleType,
+		})
+
+		require.Nil(t, err)
+		require.Nil(t, resp.Error())
+		require.Nil(t, resp)
+	})
+
+	t.Run("Read User Role", func(t *testing.T) {
+		resp, err := testTokenRoleRead(t, b, s)
+
+		require.Nil(t, err)
+		require.Nil(t, resp.Error())
+		require.NotNil(t, resp)
+		require.Equal(t, grants, resp.Data["grant_strings"])
+		require.Equal(t, "p_1234567890", resp.Data["grant_scope_id"])
+	})
+
+	t.Run("Create User Role - no roles or grants", func(t *testing.T) {
+		_, err := testTokenRoleCreate(t, b, s, roleName+"-empty", map[string]interface{}{
+			"scope_id":       scope_id,
+			"auth_method_id": auth_method_id,
+			"role_type":      roleType,
+		})
+
+		require.Error(t, err)
+	})
+}
+
+// TestUserRoleGroups checks that a user role can add users to
+// existing Boundary groups.
+func TestUserRoleGroups(t *testing.T) {
+	b, s := getTestBackend(t)
+	configureTestBackend(t, b, s, &fakeBoundary{
+		expiration: time.Now().Add(time.Hour),
+		groups:     map[string][]string{"g_1234567890": nil},
+	})
+
+	t.Run("Create User Role - pass", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+			"boundary_groups": "g_1234567890",
+			"scope_id":        scope_id,
+			"auth_method_id":  auth_method_id,
+			"role_type":       roleType,
+		})
+
+		require.Nil(t, err)
+		require.Nil(t, resp)
+
+		resp, err = testTokenRoleRead(t, b, s)
+		require.Nil(t, err)
+		require.Equal(t, []string{"g_1234567890"}, resp.Data["boundary_groups"])
+	})
+
+	t.Run("Create User Role - missing group", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName+"-missing", map[string]interface{}{
+			"boundary_groups": "g_0000000000",
+			"scope_id":        scope_id,
+			"auth_method_id":  auth_method_id,
+			"role_type":       roleType,
+		})
+
+		require.Nil(t, err)
+		require.True(t, resp.IsError())
+	})
+}
+
+// TestUserRoleTemplates checks that name templates are validated
+// when a role is written.
+func TestUserRoleTemplates(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	t.Run("Valid Templates", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+			"grant_strings":         []string{"id=*;type=*;actions=read"},
+			"scope_id":              scope_id,
+			"auth_method_id":        auth_method_id,
+			"role_type":             roleType,
+			"username_template":     `ci-{{.RoleName}}-{{random 8 | lowercase}}`,
+			"display_name_template": `{{.DisplayName}}-{{random 8 | lowercase}}`,
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		resp, err = testTokenRoleRead(t, b, s)
+		require.NoError(t, err)
+		require.Equal(t, `ci-{{.RoleName}}-{{random 8 | lowercase}}`, resp.Data["username_template"])
+		require.Equal(t, `{{.DisplayName}}-{{random 8 | lowercase}}`, resp.Data["display_name_template"])
+	})
+
+	t.Run("Display Name Not Unique", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName+"-unique", map[string]interface{}{
+			"grant_strings":         []string{"id=*;type=*;actions=read"},
+			"scope_id":              scope_id,
+			"auth_method_id":        auth_method_id,
+			"role_type":             roleType,
+			"display_name_template": `{{.DisplayName}}`,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "unique")
+	})
+
+	t.Run("Empty Display Name", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName+"-empty", map[string]interface{}{
+			"grant_strings":         []string{"id=*;type=*;actions=read"},
+			"scope_id":              scope_id,
+			"auth_method_id":        auth_method_id,
+			"role_type":             roleType,
+			"display_name_template": `{{if .Metadata.team}}{{.Metadata.team}}{{end}}`,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "empty")
+	})
+
+	t.Run("Invalid Syntax", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName+"-syntax", map[string]interface{}{
+			"grant_strings":     []string{"id=*;type=*;actions=read"},
+			"scope_id":          scope_id,
+			"auth_method_id":    auth_method_id,
+			"role_type":         roleType,
+			"username_template": `{{.RoleName`,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("Invalid Login Name", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName+"-login", map[string]interface{}{
+			"grant_strings":     []string{"id=*;type=*;actions=read"},
+			"scope_id":          scope_id,
+			"auth_method_id":    auth_method_id,
+			"role_type":         roleType,
+			"username_template": `CI-{{random 8}}`,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+}
+
+// TestUserRolePasswordPolicy checks that a role's password policy
+// must exist when the role is written.
+func TestUserRolePasswordPolicy(t *testing.T) {
+	b, s := getTestBackend(t)
+	b.System().(*logical.StaticSystemView).SetPasswordPolicy("boundary", func() (string, error) {
+		return "generated-password", nil
+	})
+
+	t.Run("Existing Policy", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+			"grant_strings":   []string{"id=*;type=*;actions=read"},
+			"scope_id":        scope_id,
+			"auth_method_id":  auth_method_id,
+			"role_type":       roleType,
+			"password_policy": "boundary",
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		resp, err = testTokenRoleRead(t, b, s)
+		require.NoError(t, err)
+		require.Equal(t, "boundary", resp.Data["password_policy"])
+	})
+
+	t.Run("Missing Policy", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName+"-missing", map[string]interface{}{
+			"grant_strings":   []string{"id=*;type=*;actions=read"},
+			"scope_id":        scope_id,
+			"auth_method_id":  auth_method_id,
+			"role_type":       roleType,
+			"password_policy": "missing",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), `"missing"`)
+	})
+}
+
+// TestUserRoleCredentialType checks the credential types a
+// user role accepts.
+func TestUserRoleCredentialType(t *testing.T) {
+	b, s := getTestBackend(t)
+	configureTestBackend(t, b, s, &fakeBoundary{expiration: time.Now().Add(time.Hour)})
+
+	t.Run("Default To Userpass", func(t *testing.T) {
+		_, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+			"boundary_roles": boundary_roles,
+			"scope_id":       scope_id,
+			"auth_method_id": auth_method_id,
+			"role_type":      roleType,
+		})
+		require.NoError(t, err)
+
+		resp, err := testTokenRoleRead(t, b, s)
+		require.NoError(t, err)
+		require.Equal(t, "userpass", resp.Data["credential_type"])
+	})
+
+	t.Run("Auth Token", func(t *testing.T) {
+		_, err := testTokenRoleUpdate(t, b, s, map[string]interface{}{
+			"role_type":       roleType,
+			"credential_type": "auth_token",
+		})
+		require.NoError(t, err)
+
+		resp, err := testTokenRoleRead(t, b, s)
+		require.NoError(t, err)
+		require.Equal(t, "auth_token", resp.Data["credential_type"])
+	})
+
+	t.Run("Invalid Type", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName+"-invalid", map[string]interface{}{
+			"boundary_roles":  boundary_roles,
+			"scope_id":        scope_id,
+			"auth_method_id":  auth_method_id,
+			"role_type":       roleType,
+			"credential_type": "certificate",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+}
+
 func TestWorkerRole(t *testing.T) {
 	b, s := getTestBackend(t)
 
@@ -0,0 +1,403 @@
+package boundarysecrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/boundary/api/accounts"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const staticRoleStoragePrefix = "static-role/"
+
+// staticRoleEntry binds a Vault role to an existing Boundary
+// password account whose password Vault rotates. The account
+// itself is never created or deleted by Vault.
+type staticRoleEntry struct {
+	Name              string        `json:"name"`
+	AccountId         string        `json:"account_id"`
+	AuthMethodId      string        `json:"auth_method_id"`
+	LoginName         string        `json:"login_name"`
+	Password          string        `json:"password"`
+	RotationPeriod    time.Duration `json:"rotation_period"`
+	LastVaultRotation time.Time     `json:"last_vault_rotation"`
+}
+
+// nextRotation returns when the account's password is next due
+// to be rotated.
+func (r *staticRoleEntry) nextRotation() time.Time {
+	return r.LastVaultRotation.Add(r.RotationPeriod)
+}
+
+func (r *staticRoleEntry) toResponseData() map[string]interface{} {
+	return map[string]interface{}{
+		"name":                r.Name,
+		"account_id":          r.AccountId,
+		"auth_method_id":      r.AuthMethodId,
+		"login_name":          r.LoginName,
+		"rotation_period":     r.RotationPeriod.Seconds(),
+		"last_vault_rotation": r.LastVaultRotation.Format(time.RFC3339),
+	}
+}
+
+func pathStaticRole(b *boundaryBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "static-role/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeLowerCaseString,
+					Description: "Name of the static role",
+					Required:    true,
+				},
+				"account_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the existing Boundary password account whose password Vault manages",
+				},
+				"rotation_period": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How often Vault rotates the account's password",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathStaticRolesRead,
+				},
+				logical.CreateOperation: &framework.PathOperation{
+					Callback:                    b.pathStaticRolesWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathStaticRolesWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback:                    b.pathStaticRolesDelete,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+				},
+			},
+			HelpSynopsis:    pathStaticRoleHelpSynopsis,
+			HelpDescription: pathStaticRoleHelpDescription,
+		},
+		{
+			Pattern: "static-role/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathStaticRolesList,
+				},
+			},
+			HelpSynopsis:    pathStaticRoleListHelpSynopsis,
+			HelpDescription: pathStaticRoleListHelpDescription,
+		},
+		{
+			Pattern: "static-creds/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeLowerCaseString,
+					Description: "Name of the static role",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathStaticCredsRead,
+				},
+			},
+			HelpSynopsis:    pathStaticCredsHelpSynopsis,
+			HelpDescription: pathStaticCredsHelpDescription,
+		},
+	}
+}
+
+func getStaticRole(ctx context.Context, s logical.Storage, name string) (*staticRoleEntry, error) {
+	if name == "" {
+		return nil, fmt.Errorf("missing static role name")
+	}
+
+	entry, err := s.Get(ctx, staticRoleStoragePrefix+name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role staticRoleEntry
+
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func setStaticRole(ctx context.Context, s logical.Storage, role *staticRoleEntry) error {
+	entry, err := logical.StorageEntryJSON(staticRoleStoragePrefix+role.Name, role)
+	if err != nil {
+		return err
+	}
+
+	if entry == nil {
+		return fmt.Errorf("failed to create storage entry for static role")
+	}
+
+	return s.Put(ctx, entry)
+}
+
+func (b *boundaryBackend) pathStaticRolesRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := getStaticRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: role.toResponseData(),
+	}, nil
+}
+
+func (b *boundaryBackend) pathStaticRolesWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.staticRoleLock.Lock()
+	defer b.staticRoleLock.Unlock()
+
+	name := d.Get("name").(string)
+
+	role, err := getStaticRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	isNew := role == nil
+	if isNew {
+		role = &staticRoleEntry{Name: name}
+	}
+
+	if accountId, ok := d.GetOk("account_id"); ok {
+		if !isNew && accountId.(string) != role.AccountId {
+			return logical.ErrorResponse("account_id cannot be changed, delete and recreate the static role instead"), nil
+		}
+		role.AccountId = accountId.(string)
+	}
+
+	if role.AccountId == "" {
+		return logical.ErrorResponse("missing account_id in static role"), nil
+	}
+
+	if rotationPeriod, ok := d.GetOk("rotation_period"); ok {
+		role.RotationPeriod = time.Duration(rotationPeriod.(int)) * time.Second
+	}
+
+	if role.RotationPeriod <= 0 {
+		return logical.ErrorResponse("rotation_period must be greater than zero"), nil
+	}
+
+	if isNew {
+		client, err := b.getClient(ctx, req.Storage)
+		if err != nil {
+			return nil, fmt.Errorf("error getting client: %w", err)
+		}
+
+		arr, err := accounts.NewClient(client.Client).Read(ctx, role.AccountId)
+		if err != nil {
+			return nil, fmt.Errorf("error reading account %q: %w", role.AccountId, err)
+		}
+
+		loginName, ok := arr.Item.Attributes["login_name"].(string)
+		if !ok || loginName == "" {
+			return logical.ErrorResponse("account %q is not a password account", role.AccountId), nil
+		}
+
+		role.AuthMethodId = arr.Item.AuthMethodId
+		role.LoginName = loginName
+
+		// Take ownership of the password straight away, so that
+		// nobody else knows the credentials Vault hands out
+		return nil, b.rotateStaticRole(ctx, req.Storage, role)
+	}
+
+	return nil, setStaticRole(ctx, req.Storage, role)
+}
+
+func (b *boundaryBackend) pathStaticRolesDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.staticRoleLock.Lock()
+	defer b.staticRoleLock.Unlock()
+
+	err := req.Storage.Delete(ctx, staticRoleStoragePrefix+d.Get("name").(string))
+	if err != nil {
+		return nil, fmt.Errorf("error deleting static role: %w", err)
+	}
+
+	return nil, nil
+}
+
+func (b *boundaryBackend) pathStaticRolesList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, staticRoleStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *boundaryBackend) pathStaticCredsRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := getStaticRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	if role == nil {
+		return nil, errors.New("error retrieving static role: role is nil")
+	}
+
+	ttl := time.Until(role.nextRotation())
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"account_id":          role.AccountId,
+			"auth_method_id":      role.AuthMethodId,
+			"login_name":          role.LoginName,
+			"password":            role.Password,
+			"last_vault_rotation": role.LastVaultRotation.Format(time.RFC3339),
+			"rotation_period":     role.RotationPeriod.Seconds(),
+			"ttl":                 int64(ttl.Seconds()),
+		},
+	}, nil
+}
+
+// rotateStaticRole sets a new password on the role's account and
+// stores it. The caller must hold b.staticRoleLock.
+func (b *boundaryBackend) rotateStaticRole(ctx context.Context, s logical.Storage, role *staticRoleEntry) error {
+	config, err := getConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if config == nil {
+		return errors.New("backend not configured")
+	}
+
+	client, err := b.getClient(ctx, s)
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	newPassword, err := b.generatePassword(ctx, config.PasswordPolicy)
+	if err != nil {
+		return err
+	}
+
+	// Both passwords are recorded before Boundary is changed, so
+	// walRollback can bring the account and the role back in line
+	// if Vault stops before the role is stored.
+	walId, err := framework.PutWAL(ctx, s, walStaticPasswordKind, &walStaticPassword{
+		Name:             role.Name,
+		AccountId:        role.AccountId,
+		AuthMethodId:     role.AuthMethodId,
+		LoginName:        role.LoginName,
+		RotationPeriod:   int64(role.RotationPeriod.Seconds()),
+		NewPassword:      newPassword,
+		PreviousPassword: role.Password,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing WAL entry: %w", err)
+	}
+
+	acr := accounts.NewClient(client.Client)
+	_, err = acr.SetPassword(ctx, role.AccountId, newPassword, 0, accounts.WithAutomaticVersioning(true))
+	if err != nil {
+		return discardRejectedWAL(ctx, s, walId, fmt.Errorf("error setting password for account %q: %w", role.AccountId, err))
+	}
+
+	previous := *role
+	role.Password = newPassword
+	role.LastVaultRotation = time.Now()
+
+	if err := setStaticRole(ctx, s, role); err != nil {
+		*role = previous
+
+		// A new role has no previous password to put back, so the
+		// WAL entry is left for walRollback to store it
+		if previous.Password == "" {
+			return fmt.Errorf("error storing static role: %w", err)
+		}
+
+		// Put the old password back so the stored role keeps working
+		_, rerr := acr.SetPassword(ctx, role.AccountId, previous.Password, 0, accounts.WithAutomaticVersioning(true))
+		if rerr != nil {
+			return fmt.Errorf("error storing static role: %v; error restoring previous password: %w", err, rerr)
+		}
+		return fmt.Errorf("error storing static role: %w", err)
+	}
+
+	if err := framework.DeleteWAL(ctx, s, walId); err != nil {
+		b.Logger().Warn("unable to remove static role rotation WAL entry", "role", role.Name, "error", err)
+	}
+
+	return nil
+}
+
+// rotateStaticRolesIfDue rotates the password of every static role
+// whose rotation period has elapsed. A failure is logged and retried
+// on the next run rather than stopping the other roles rotating.
+func (b *boundaryBackend) rotateStaticRolesIfDue(ctx context.Context, s logical.Storage) error {
+	b.staticRoleLock.Lock()
+	defer b.staticRoleLock.Unlock()
+
+	names, err := s.List(ctx, staticRoleStoragePrefix)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, name := range names {
+		role, err := getStaticRole(ctx, s, name)
+		if err != nil {
+			return err
+		}
+
+		if role == nil || now.Before(role.nextRotation()) {
+			continue
+		}
+
+		if err := b.rotateStaticRole(ctx, s, role); err != nil {
+			b.Logger().Error("static role rotation failed", "role", name, "error", err)
+			continue
+		}
+
+		b.Logger().Info("rotated static role", "role", name)
+	}
+
+	return nil
+}
+
+const (
+	pathStaticRoleHelpSynopsis    = `Manages Vault roles for existing Boundary password accounts.`
+	pathStaticRoleHelpDescription = `
+This path binds a Vault role to an existing Boundary password
+account. Vault rotates the account's password when the role is
+created and then every rotation_period. Deleting the role stops
+the rotation but leaves the account in place.
+`
+	pathStaticRoleListHelpSynopsis    = `List the existing static roles in Boundary backend`
+	pathStaticRoleListHelpDescription = `Static roles will be listed by the role name.`
+
+	pathStaticCredsHelpSynopsis    = `Read the current credentials of a static role.`
+	pathStaticCredsHelpDescription = `
+This path returns the current password of the Boundary account
+bound to a static role, along with when Vault last rotated it and
+the number of seconds until it next will.
+`
+)
@@ -0,0 +1,208 @@
+package boundarysecrets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	staticRoleName  = "teststatic"
+	staticAccountId = "acctpw_0987654321"
+)
+
+// TestStaticRole checks that static roles take over an existing
+// account's password and rotate it when due.
+func TestStaticRole(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	fake := &fakeBoundary{
+		expiration: time.Now().Add(time.Hour),
+		password:   Password,
+	}
+	configureTestBackend(t, b, s, fake)
+
+	t.Run("Missing Account", func(t *testing.T) {
+		resp, err := testStaticRoleWrite(t, b, s, map[string]interface{}{
+			"rotation_period": "1h",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("Create Static Role", func(t *testing.T) {
+		resp, err := testStaticRoleWrite(t, b, s, map[string]interface{}{
+			"account_id":      staticAccountId,
+			"rotation_period": "1h",
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+		require.NotEmpty(t, fake.accountPasswords[staticAccountId])
+
+		walIds, err := framework.ListWAL(ctx, s)
+		require.NoError(t, err)
+		require.Empty(t, walIds)
+	})
+
+	t.Run("Read Static Role", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-role/" + staticRoleName,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, staticAccountId, resp.Data["account_id"])
+		require.Equal(t, "static-"+staticAccountId, resp.Data["login_name"])
+		require.Equal(t, float64(3600), resp.Data["rotation_period"])
+		require.NotContains(t, resp.Data, "password")
+	})
+
+	t.Run("Read Static Creds", func(t *testing.T) {
+		resp := testStaticCredsRead(t, b, s)
+		require.Equal(t, fake.accountPasswords[staticAccountId], resp.Data["password"])
+		require.InDelta(t, 3600, resp.Data["ttl"], 5)
+		require.NotEmpty(t, resp.Data["last_vault_rotation"])
+	})
+
+	t.Run("Cannot Change Account", func(t *testing.T) {
+		resp, err := testStaticRoleWrite(t, b, s, map[string]interface{}{
+			"account_id": "acctpw_1111111111",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("Rotate When Due", func(t *testing.T) {
+		previous := fake.accountPasswords[staticAccountId]
+
+		require.NoError(t, b.periodicFunc(ctx, &logical.Request{Storage: s}))
+		require.Equal(t, previous, fake.accountPasswords[staticAccountId])
+
+		role, err := getStaticRole(ctx, s, staticRoleName)
+		require.NoError(t, err)
+		role.LastVaultRotation = time.Now().Add(-2 * time.Hour)
+		require.NoError(t, setStaticRole(ctx, s, role))
+
+		require.NoError(t, b.periodicFunc(ctx, &logical.Request{Storage: s}))
+		require.NotEqual(t, previous, fake.accountPasswords[staticAccountId])
+
+		resp := testStaticCredsRead(t, b, s)
+		require.Equal(t, fake.accountPasswords[staticAccountId], resp.Data["password"])
+	})
+
+	t.Run("Rotate With Password Policy", func(t *testing.T) {
+		b.System().(*logical.StaticSystemView).SetPasswordPolicy("boundary", func() (string, error) {
+			return "policy-password", nil
+		})
+		require.NoError(t, testConfigUpdate(t, b, s, map[string]interface{}{
+			"password_policy": "boundary",
+		}))
+
+		role, err := getStaticRole(ctx, s, staticRoleName)
+		require.NoError(t, err)
+
+		require.NoError(t, b.rotateStaticRole(ctx, s, role))
+		require.Equal(t, "policy-password", fake.accountPasswords[staticAccountId])
+
+		resp := testStaticCredsRead(t, b, s)
+		require.Equal(t, "policy-password", resp.Data["password"])
+	})
+
+	t.Run("Rotate Storage Failure", func(t *testing.T) {
+		previous := fake.accountPasswords[staticAccountId]
+
+		role, err := getStaticRole(ctx, s, staticRoleName)
+		require.NoError(t, err)
+
+		err = b.rotateStaticRole(ctx, &failingStorage{Storage: s, key: staticRoleStoragePrefix + staticRoleName}, role)
+		require.Error(t, err)
+		require.Equal(t, previous, fake.accountPasswords[staticAccountId])
+
+		resp := testStaticCredsRead(t, b, s)
+		require.Equal(t, previous, resp.Data["password"])
+
+		walIds, err := framework.ListWAL(ctx, s)
+		require.NoError(t, err)
+		require.Len(t, walIds, 1)
+
+		wal, err := framework.GetWAL(ctx, s, walIds[0])
+		require.NoError(t, err)
+		require.Equal(t, walStaticPasswordKind, wal.Kind)
+		require.NoError(t, framework.DeleteWAL(ctx, s, walIds[0]))
+	})
+
+	t.Run("Rollback Restores Stored Password", func(t *testing.T) {
+		previous := fake.accountPasswords[staticAccountId]
+
+		// Boundary has the new password, but Vault stopped before
+		// storing it
+		fake.accountPasswords[staticAccountId] = "never-stored"
+
+		err := b.walRollback(ctx, &logical.Request{Storage: s}, walStaticPasswordKind, map[string]interface{}{
+			"name":              staticRoleName,
+			"account_id":        staticAccountId,
+			"new_password":      "never-stored",
+			"previous_password": previous,
+		})
+		require.NoError(t, err)
+		require.Equal(t, previous, fake.accountPasswords[staticAccountId])
+	})
+
+	t.Run("Rollback Stores New Role", func(t *testing.T) {
+		err := b.walRollback(ctx, &logical.Request{Storage: s}, walStaticPasswordKind, map[string]interface{}{
+			"name":            "newstatic",
+			"account_id":      "acctpw_1111111111",
+			"auth_method_id":  authMethodId,
+			"login_name":      "static-acctpw_1111111111",
+			"rotation_period": 3600,
+			"new_password":    "never-stored",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "never-stored", fake.accountPasswords["acctpw_1111111111"])
+
+		role, err := getStaticRole(ctx, s, "newstatic")
+		require.NoError(t, err)
+		require.Equal(t, "never-stored", role.Password)
+		require.Equal(t, time.Hour, role.RotationPeriod)
+	})
+
+	t.Run("Delete Static Role", func(t *testing.T) {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "static-role/" + staticRoleName,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+
+		role, err := getStaticRole(ctx, s, staticRoleName)
+		require.NoError(t, err)
+		require.Nil(t, role)
+	})
+}
+
+func testStaticRoleWrite(t *testing.T, b *boundaryBackend, s logical.Storage, d map[string]interface{}) (*logical.Response, error) {
+	t.Helper()
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "static-role/" + staticRoleName,
+		Data:      d,
+		Storage:   s,
+	})
+}
+
+func testStaticCredsRead(t *testing.T, b *boundaryBackend, s logical.Storage) *logical.Response {
+	t.Helper()
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "static-creds/" + staticRoleName,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	return resp
+}
@@ -0,0 +1,153 @@
+package boundarysecrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/boundary/api/workers"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathWorkerCA extends the Vault API with `/worker-ca` endpoints
+// that read and rotate the certificate authority Boundary uses to
+// authenticate PKI workers in a worker role's scope.
+func pathWorkerCA(b *boundaryBackend) []*framework.Path {
+	fields := map[string]*framework.FieldSchema{
+		"role": {
+			Type:        framework.TypeLowerCaseString,
+			Description: "Name of the worker role whose scope the certificate authority belongs to",
+			Required:    true,
+		},
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "worker-ca/read",
+			Fields:  fields,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathWorkerCARead,
+				},
+			},
+			HelpSynopsis:    pathWorkerCAReadHelpSyn,
+			HelpDescription: pathWorkerCAReadHelpDesc,
+		},
+		{
+			Pattern: "worker-ca/rotate",
+			Fields:  fields,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathWorkerCARotate,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+				},
+			},
+			HelpSynopsis:    pathWorkerCARotateHelpSyn,
+			HelpDescription: pathWorkerCARotateHelpDesc,
+		},
+	}
+}
+
+func (b *boundaryBackend) pathWorkerCARead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleEntry, resp, err := b.workerCARole(ctx, req, d)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	crr, err := workers.NewClient(client.Client).ReadCA(ctx, roleEntry.ScopeId)
+	if err != nil {
+		return errorResponse(fmt.Errorf("error reading worker certificate authority: %w", err))
+	}
+
+	return &logical.Response{
+		Data: workerCAResponseData(roleEntry.ScopeId, crr.Item),
+	}, nil
+}
+
+func (b *boundaryBackend) pathWorkerCARotate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleEntry, resp, err := b.workerCARole(ctx, req, d)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	crr, err := workers.NewClient(client.Client).ReinitializeCA(ctx, roleEntry.ScopeId)
+	if err != nil {
+		return errorResponse(fmt.Errorf("error reinitializing worker certificate authority: %w", err))
+	}
+
+	b.Logger().Info("reinitialized worker certificate authority", "role", roleEntry.Name, "scope_id", roleEntry.ScopeId)
+
+	return &logical.Response{
+		Data: workerCAResponseData(roleEntry.ScopeId, crr.Item),
+	}, nil
+}
+
+// workerCARole returns the worker role named in the request, or an
+// error response if there is no such worker role.
+func (b *boundaryBackend) workerCARole(ctx context.Context, req *logical.Request, d *framework.FieldData) (*boundaryRoleEntry, *logical.Response, error) {
+	roleName := d.Get("role").(string)
+	if roleName == "" {
+		return nil, logical.ErrorResponse("missing role"), nil
+	}
+
+	roleEntry, err := b.getRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error retrieving role: %w", err)
+	}
+
+	if roleEntry == nil {
+		return nil, nil, errors.New("error retrieving role: role is nil")
+	}
+
+	if roleEntry.RoleType != "worker" {
+		return nil, logical.ErrorResponse("worker certificate authorities can only be managed through worker roles"), nil
+	}
+
+	return roleEntry, nil, nil
+}
+
+func workerCAResponseData(scopeId string, ca *workers.CertificateAuthority) map[string]interface{} {
+	certs := make([]map[string]interface{}, 0, len(ca.Certs))
+	for _, cert := range ca.Certs {
+		certs = append(certs, map[string]interface{}{
+			"id":                cert.Id,
+			"public_key_sha256": cert.PublicKeySha256,
+			"not_before_time":   cert.NotBeforeTime.Format(time.RFC3339),
+			"not_after_time":    cert.NotAfterTime.Format(time.RFC3339),
+		})
+	}
+
+	return map[string]interface{}{
+		"scope_id": scopeId,
+		"certs":    certs,
+	}
+}
+
+const (
+	pathWorkerCAReadHelpSyn  = `Read the certificate authority for PKI workers in a worker role's scope.`
+	pathWorkerCAReadHelpDesc = `
+This path returns the certificates of the Boundary certificate
+authority that authenticates PKI workers in the scope of the
+given worker role.
+`
+	pathWorkerCARotateHelpSyn  = `Rotate the certificate authority for PKI workers in a worker role's scope.`
+	pathWorkerCARotateHelpDesc = `
+This path reinitializes the Boundary certificate authority that
+authenticates PKI workers in the scope of the given worker role,
+and returns the new certificates. Existing PKI workers must be
+registered again afterwards.
+`
+)
@@ -0,0 +1,69 @@
+package boundarysecrets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerCA(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	fake := &fakeBoundary{expiration: time.Now().Add(time.Hour)}
+	configureTestBackend(t, b, s, fake)
+
+	_, err := testTokenRoleCreate(t, b, s, workerRoleName, map[string]interface{}{
+		"scope_id":  scope_id,
+		"role_type": "worker",
+	})
+	require.NoError(t, err)
+
+	t.Run("Read", func(t *testing.T) {
+		resp, err := testWorkerCA(t, b, s, logical.ReadOperation, "read", workerRoleName)
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+		require.Equal(t, scope_id, fake.caScope)
+		require.Equal(t, scope_id, resp.Data["scope_id"])
+
+		certs := resp.Data["certs"].([]map[string]interface{})
+		require.Len(t, certs, 1)
+		require.Equal(t, "roots_0", certs[0]["id"])
+		require.Equal(t, "2023-01-01T00:00:00Z", certs[0]["not_after_time"])
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		resp, err := testWorkerCA(t, b, s, logical.UpdateOperation, "rotate", workerRoleName)
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+		require.Equal(t, 1, fake.caVersion)
+		require.Equal(t, "roots_1", resp.Data["certs"].([]map[string]interface{})[0]["id"])
+	})
+
+	t.Run("User Role", func(t *testing.T) {
+		_, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+			"scope_id":       scope_id,
+			"role_type":      roleType,
+			"auth_method_id": auth_method_id,
+			"grant_strings":  "id=*;type=*;actions=read",
+		})
+		require.NoError(t, err)
+
+		resp, err := testWorkerCA(t, b, s, logical.UpdateOperation, "rotate", roleName)
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		require.Equal(t, 1, fake.caVersion)
+	})
+}
+
+func testWorkerCA(t *testing.T, b *boundaryBackend, s logical.Storage, op logical.Operation, action string, role string) (*logical.Response, error) {
+	t.Helper()
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      "worker-ca/" + action,
+		Data:      map[string]interface{}{"role": role},
+		Storage:   s,
+	})
+}
@@ -0,0 +1,134 @@
+package boundarysecrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathWorkerRegister extends the Vault API with a
+// `/worker-register` endpoint for a worker role, which registers
+// a worker from the auth token the worker generated itself.
+func pathWorkerRegister(b *boundaryBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "worker-register/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the role",
+				Required:    true,
+			},
+			"worker_generated_auth_token": {
+				Type:        framework.TypeString,
+				Description: "Registration request the worker generated for itself",
+				Required:    true,
+			},
+			"worker_name": {
+				Type:        framework.TypeString,
+				Description: "Name of Boundary Worker",
+				Required:    false,
+			},
+			"description": {
+				Type:        framework.TypeString,
+				Description: "Short description of the worker",
+				Required:    false,
+			},
+			"worker_tags": {
+				Type:        framework.TypeMap,
+				Description: "Tags to add to the worker, as a map of keys to lists of values. Must be allowed by the role's allowed_worker_tags.",
+				Required:    false,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathWorkerRegisterWrite,
+			},
+		},
+		HelpSynopsis:    pathWorkerRegisterHelpSyn,
+		HelpDescription: pathWorkerRegisterHelpDesc,
+	}
+}
+
+func (b *boundaryBackend) pathWorkerRegisterWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("name").(string)
+	authToken := d.Get("worker_generated_auth_token").(string)
+	workerName := d.Get("worker_name").(string)
+	workerDescription := d.Get("description").(string)
+
+	if authToken == "" {
+		return logical.ErrorResponse("missing worker_generated_auth_token"), nil
+	}
+
+	if workerDescription == "" {
+		workerDescription = "Generated by Vault"
+	}
+
+	roleEntry, err := b.getRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving role: %w", err)
+	}
+
+	if roleEntry == nil {
+		return nil, errors.New("error retrieving role: role is nil")
+	}
+
+	if roleEntry.RoleType != "worker" {
+		return logical.ErrorResponse("workers can only be registered with worker roles"), nil
+	}
+
+	workerTags, err := parseWorkerTags(d.Get("worker_tags").(map[string]interface{}))
+	if err != nil {
+		return logical.ErrorResponse("invalid worker_tags: %s", err), nil
+	}
+
+	if err := roleEntry.checkWorkerTags(workerTags); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	workerName, err = b.workerName(ctx, req, client, roleEntry, workerName)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	tags := mergeWorkerTags(roleEntry.WorkerTags, workerTags)
+
+	worker, err := createWorkerLed(ctx, client, roleEntry.ScopeId, authToken, workerName, workerDescription, tags)
+	if err != nil {
+		return errorResponse(classifyError(fmt.Errorf("error registering Boundary worker: %w", err)))
+	}
+
+	if err := b.trackWorkerActivation(ctx, req.Storage, roleEntry, worker.WorkerId); err != nil {
+		return nil, err
+	}
+
+	resp := b.workerResponse(roleEntry, worker)
+
+	if roleEntry.TTL > 0 {
+		resp.Secret.TTL = roleEntry.TTL
+	}
+
+	if roleEntry.MaxTTL > 0 {
+		resp.Secret.MaxTTL = roleEntry.MaxTTL
+	}
+
+	return resp, nil
+}
+
+const pathWorkerRegisterHelpSyn = `
+Register a Boundary worker from its own registration request.
+`
+
+const pathWorkerRegisterHelpDesc = `
+This path registers a worker that generated its own auth token
+with Boundary, in the scope of a worker role. The worker is
+deleted when the lease is revoked, or once the role's
+activation_timeout has passed if it has not yet connected.
+`
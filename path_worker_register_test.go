@@ -0,0 +1,89 @@
+package boundarysecrets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWorkerRegister checks that a worker-led registration issues
+// a worker lease that deletes the worker when revoked.
+func TestWorkerRegister(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	fake := &fakeBoundary{expiration: time.Now().Add(time.Hour)}
+	configureTestBackend(t, b, s, fake)
+
+	_, err := testTokenRoleCreate(t, b, s, workerRoleName, map[string]interface{}{
+		"scope_id":  scope_id,
+		"role_type": "worker",
+		"ttl":       testTTL,
+	})
+	require.NoError(t, err)
+
+	t.Run("Missing Auth Token", func(t *testing.T) {
+		resp, err := testWorkerRegister(t, b, s, map[string]interface{}{})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("Register And Revoke", func(t *testing.T) {
+		resp, err := testWorkerRegister(t, b, s, map[string]interface{}{
+			"worker_generated_auth_token": "GzusqckarbczHoLGQ4UA25uSRsk",
+			"worker_name":                 "local worker",
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+		require.Equal(t, "GzusqckarbczHoLGQ4UA25uSRsk", fake.workerAuthToken)
+		require.Equal(t, "w_0987654321", resp.Data["worker_id"])
+		require.Equal(t, "local worker", resp.Data["worker_name"])
+		require.NotContains(t, resp.Data, "activation_token")
+		require.Equal(t, time.Duration(testTTL)*time.Second, resp.Secret.TTL)
+
+		_, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RevokeOperation,
+			Secret:    resp.Secret,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"/v1/workers/w_0987654321"}, fake.deleted)
+	})
+
+	t.Run("Track Activation", func(t *testing.T) {
+		_, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "role/" + workerRoleName,
+			Data: map[string]interface{}{
+				"role_type":          "worker",
+				"activation_timeout": "10m",
+			},
+			Storage: s,
+		})
+		require.NoError(t, err)
+
+		resp, err := testWorkerRegister(t, b, s, map[string]interface{}{
+			"worker_generated_auth_token": "GzusqckarbczHoLGQ4UA25uSRsk",
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+
+		pending, err := getPendingWorker(context.Background(), s, "w_0987654321")
+		require.NoError(t, err)
+		require.NotNil(t, pending)
+		require.WithinDuration(t, time.Now().Add(10*time.Minute), pending.Deadline, time.Minute)
+	})
+
+}
+
+func testWorkerRegister(t *testing.T, b *boundaryBackend, s logical.Storage, d map[string]interface{}) (*logical.Response, error) {
+	t.Helper()
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "worker-register/" + workerRoleName,
+		Data:      d,
+		Storage:   s,
+	})
+}
@@ -0,0 +1,90 @@
+package boundarysecrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	wrapping "github.com/hashicorp/go-kms-wrapping/v2"
+	"github.com/hashicorp/go-kms-wrapping/v2/aead"
+	"github.com/hashicorp/hcl"
+)
+
+const recoveryPurpose = "recovery"
+
+// recoveryKmsBlock is a Boundary `kms` stanza, as found in the
+// controller's configuration file.
+type recoveryKmsBlock struct {
+	Type     string      `hcl:",key"`
+	Purpose  interface{} `hcl:"purpose"`
+	AeadType string      `hcl:"aead_type"`
+	Key      string      `hcl:"key"`
+	KeyId    string      `hcl:"key_id"`
+}
+
+// hasPurpose reports whether the block lists the given purpose,
+// which Boundary accepts as either a string or a list.
+func (k *recoveryKmsBlock) hasPurpose(purpose string) bool {
+	switch p := k.Purpose.(type) {
+	case string:
+		return p == purpose
+	case []interface{}:
+		for _, v := range p {
+			if v == purpose {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recoveryKmsWrapper parses the HCL for a Boundary recovery KMS
+// and returns the wrapper used to sign recovery tokens. Only the
+// aead type is supported, since the key must live in Vault.
+func recoveryKmsWrapper(ctx context.Context, src string) (wrapping.Wrapper, error) {
+	var parsed struct {
+		Kms []*recoveryKmsBlock `hcl:"kms"`
+	}
+	if err := hcl.Decode(&parsed, src); err != nil {
+		return nil, fmt.Errorf("error parsing recovery KMS HCL: %w", err)
+	}
+
+	var block *recoveryKmsBlock
+	for _, k := range parsed.Kms {
+		if k.hasPurpose(recoveryPurpose) {
+			block = k
+			break
+		}
+	}
+
+	if block == nil {
+		return nil, errors.New("no kms block with purpose \"recovery\" found")
+	}
+
+	if block.Type != "aead" {
+		return nil, fmt.Errorf("unsupported recovery kms type %q, must be \"aead\"", block.Type)
+	}
+
+	if block.Key == "" {
+		return nil, errors.New("recovery kms block is missing a key")
+	}
+
+	aeadType := block.AeadType
+	if aeadType == "" {
+		aeadType = wrapping.AeadTypeAesGcm.String()
+	}
+
+	wrapper := aead.NewWrapper()
+	_, err := wrapper.SetConfig(ctx,
+		wrapping.WithKeyId(block.KeyId),
+		wrapping.WithConfigMap(map[string]string{
+			"aead_type": aeadType,
+			"key":       block.Key,
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring recovery kms: %w", err)
+	}
+
+	return wrapper, nil
+}
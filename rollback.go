@@ -0,0 +1,380 @@
+package boundarysecrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	boundary "github.com/hashicorp/boundary/api"
+	"github.com/hashicorp/boundary/api/accounts"
+	"github.com/hashicorp/boundary/api/roles"
+	"github.com/hashicorp/boundary/api/scopes"
+	"github.com/hashicorp/boundary/api/users"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	walAccountKind        = "account"
+	walUserKind           = "user"
+	walRolePrincipalKind  = "role_principal"
+	walRoleKind           = "role"
+	walGroupMemberKind    = "group_member"
+	walScopeKind          = "scope"
+	walRootPasswordKind   = "root_password"
+	walStaticPasswordKind = "static_password"
+
+	// minRollbackAge is how long a WAL entry must exist before
+	// the rollback handler will attempt to undo it. This gives
+	// in-flight credential requests time to commit their entries.
+	minRollbackAge = 5 * time.Minute
+)
+
+// walAccount records an account that is about to be created.
+// The account ID is not known until Boundary responds, so the
+// account is looked up by login name on rollback.
+type walAccount struct {
+	AuthMethodId string `mapstructure:"auth_method_id" json:"auth_method_id"`
+	LoginName    string `mapstructure:"login_name" json:"login_name"`
+}
+
+// walUser records a user that is about to be created. Like
+// walAccount, the user is looked up by name on rollback.
+type walUser struct {
+	ScopeId string `mapstructure:"scope_id" json:"scope_id"`
+	Name    string `mapstructure:"name" json:"name"`
+}
+
+// walRole records a Boundary role that is about to be created
+// from a Vault role's grant strings. It is looked up by name.
+type walRole struct {
+	ScopeId string `mapstructure:"scope_id" json:"scope_id"`
+	Name    string `mapstructure:"name" json:"name"`
+}
+
+// walRolePrincipal records a principal that is about to be
+// added to an existing Boundary role.
+type walRolePrincipal struct {
+	RoleId      string `mapstructure:"role_id" json:"role_id"`
+	PrincipalId string `mapstructure:"principal_id" json:"principal_id"`
+}
+
+// walGroupMember records a user that is about to be added to
+// an existing Boundary group.
+type walGroupMember struct {
+	GroupId  string `mapstructure:"group_id" json:"group_id"`
+	MemberId string `mapstructure:"member_id" json:"member_id"`
+}
+
+// walScope records a scope that is about to be created for a
+// scope role. It is looked up in its parent scope by name and by
+// its description, which is unique to the request.
+type walScope struct {
+	ScopeId     string `mapstructure:"scope_id" json:"scope_id"`
+	Name        string `mapstructure:"name" json:"name"`
+	Description string `mapstructure:"description" json:"description"`
+}
+
+// walRootPassword records a root password that is about to be
+// set in Boundary but is not yet stored in the config.
+type walRootPassword struct {
+	NewPassword string `mapstructure:"new_password" json:"new_password"`
+}
+
+// walStaticPassword records a password that is about to be set on
+// a static role's account but is not yet stored in the role. The
+// account details are kept so a new role can still be stored.
+type walStaticPassword struct {
+	Name             string `mapstructure:"name" json:"name"`
+	AccountId        string `mapstructure:"account_id" json:"account_id"`
+	AuthMethodId     string `mapstructure:"auth_method_id" json:"auth_method_id"`
+	LoginName        string `mapstructure:"login_name" json:"login_name"`
+	RotationPeriod   int64  `mapstructure:"rotation_period" json:"rotation_period"`
+	NewPassword      string `mapstructure:"new_password" json:"new_password"`
+	PreviousPassword string `mapstructure:"previous_password" json:"previous_password"`
+}
+
+// walRollback cleans up Boundary resources left behind by a
+// credential request that failed part way through.
+func (b *boundaryBackend) walRollback(ctx context.Context, req *logical.Request, kind string, data interface{}) error {
+	// The client may not be able to log in until the root
+	// password is rolled back, so this is handled first.
+	if kind == walRootPasswordKind {
+		var entry walRootPassword
+		if err := mapstructure.Decode(data, &entry); err != nil {
+			return err
+		}
+		return b.rollbackRootPassword(ctx, req.Storage, &entry)
+	}
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	switch kind {
+	case walAccountKind:
+		var entry walAccount
+		if err := mapstructure.Decode(data, &entry); err != nil {
+			return err
+		}
+		return rollbackAccount(ctx, client, &entry)
+	case walUserKind:
+		var entry walUser
+		if err := mapstructure.Decode(data, &entry); err != nil {
+			return err
+		}
+		return rollbackUser(ctx, client, &entry)
+	case walRolePrincipalKind:
+		var entry walRolePrincipal
+		if err := mapstructure.Decode(data, &entry); err != nil {
+			return err
+		}
+		return rollbackRolePrincipal(ctx, client, &entry)
+	case walRoleKind:
+		var entry walRole
+		if err := mapstructure.Decode(data, &entry); err != nil {
+			return err
+		}
+		return rollbackRole(ctx, client, &entry)
+	case walGroupMemberKind:
+		var entry walGroupMember
+		if err := mapstructure.Decode(data, &entry); err != nil {
+			return err
+		}
+		return rollbackGroupMember(ctx, client, &entry)
+	case walScopeKind:
+		var entry walScope
+		if err := mapstructure.Decode(data, &entry); err != nil {
+			return err
+		}
+		return rollbackScope(ctx, client, &entry)
+	case walStaticPasswordKind:
+		var entry walStaticPassword
+		if err := mapstructure.Decode(data, &entry); err != nil {
+			return err
+		}
+		return b.rollbackStaticPassword(ctx, req.Storage, client, &entry)
+	default:
+		return fmt.Errorf("unknown WAL entry kind %q", kind)
+	}
+}
+
+// discardRejectedWAL removes the WAL entry for a create that Boundary
+// rejected with a 4xx. Nothing was created, and as resources are found
+// by name on rollback, keeping the entry could remove an existing
+// resource with the name that caused the rejection. Any other error
+// may have followed a successful create, so the entry is kept for
+// walRollback. It returns createErr.
+func discardRejectedWAL(ctx context.Context, s logical.Storage, walId string, createErr error) error {
+	apiErr := boundary.AsServerError(createErr)
+	if apiErr == nil || apiErr.Response() == nil {
+		return createErr
+	}
+
+	if status := apiErr.Response().StatusCode(); status < http.StatusBadRequest || status >= http.StatusInternalServerError {
+		return createErr
+	}
+
+	if err := framework.DeleteWAL(ctx, s, walId); err != nil {
+		return fmt.Errorf("%v; error removing WAL entry: %w", createErr, err)
+	}
+
+	return createErr
+}
+
+func rollbackAccount(ctx context.Context, c *boundaryClient, entry *walAccount) error {
+	acr := accounts.NewClient(c.Client)
+
+	filter := filterEquals("/item/attributes/login_name", entry.LoginName)
+	alr, err := acr.List(ctx, entry.AuthMethodId, accounts.WithFilter(filter))
+	if err != nil {
+		return err
+	}
+
+	for _, account := range alr.Items {
+		if _, err := acr.Delete(ctx, account.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rollbackUser(ctx context.Context, c *boundaryClient, entry *walUser) error {
+	ucr := users.NewClient(c.Client)
+
+	filter := filterEquals("/item/name", entry.Name)
+	ulr, err := ucr.List(ctx, entry.ScopeId, users.WithFilter(filter))
+	if err != nil {
+		return err
+	}
+
+	for _, user := range ulr.Items {
+		if _, err := ucr.Delete(ctx, user.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rollbackRole(ctx context.Context, c *boundaryClient, entry *walRole) error {
+	rcr := roles.NewClient(c.Client)
+
+	filter := filterEquals("/item/name", entry.Name)
+	rlr, err := rcr.List(ctx, entry.ScopeId, roles.WithFilter(filter))
+	if err != nil {
+		return err
+	}
+
+	for _, role := range rlr.Items {
+		if _, err := rcr.Delete(ctx, role.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rollbackScope(ctx context.Context, c *boundaryClient, entry *walScope) error {
+	scr := scopes.NewClient(c.Client)
+
+	filter := filterEquals("/item/name", entry.Name) + " and " + filterEquals("/item/description", entry.Description)
+	slr, err := scr.List(ctx, entry.ScopeId, scopes.WithFilter(filter))
+	if err != nil {
+		return err
+	}
+
+	for _, scope := range slr.Items {
+		if _, err := scr.Delete(ctx, scope.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rollbackRolePrincipal(ctx context.Context, c *boundaryClient, entry *walRolePrincipal) error {
+	rcr := roles.NewClient(c.Client)
+
+	// Deleting the role or the user also removes the principal,
+	// so it may already be gone by the time this runs.
+	rrr, err := rcr.Read(ctx, entry.RoleId)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, id := range rrr.Item.PrincipalIds {
+		if id == entry.PrincipalId {
+			_, err := rcr.RemovePrincipals(ctx, entry.RoleId, 0, []string{entry.PrincipalId}, roles.WithAutomaticVersioning(true))
+			if isNotFound(err) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rollbackGroupMember(ctx context.Context, c *boundaryClient, entry *walGroupMember) error {
+	return removeGroupMember(ctx, c, entry.GroupId, entry.MemberId)
+}
+
+// rollbackRootPassword stores the new root password if Boundary
+// accepted it but the rotation failed before the config was saved.
+func (b *boundaryBackend) rollbackRootPassword(ctx context.Context, s logical.Storage, entry *walRootPassword) error {
+	b.rotationLock.Lock()
+	defer b.rotationLock.Unlock()
+
+	config, err := getConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if config == nil || config.Password == entry.NewPassword {
+		return nil
+	}
+
+	newConfig := *config
+	newConfig.Password = entry.NewPassword
+
+	if _, err := newClient(&newConfig); err != nil {
+		// Boundary never took the new password, or it was put back
+		if _, cerr := newClient(config); cerr != nil {
+			return fmt.Errorf("error logging in with the new root password: %v; or the stored root password: %w", err, cerr)
+		}
+		return nil
+	}
+
+	entryJSON, err := logical.StorageEntryJSON(configStoragePath, &newConfig)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Put(ctx, entryJSON); err != nil {
+		return err
+	}
+
+	b.reset()
+
+	return nil
+}
+
+// rollbackStaticPassword brings a static role's account back in line
+// with the stored role after a rotation failed part way through. An
+// existing role's stored password is set on the account again. A new
+// role has no previous password Vault knows, so it is stored with the
+// new password instead.
+func (b *boundaryBackend) rollbackStaticPassword(ctx context.Context, s logical.Storage, c *boundaryClient, entry *walStaticPassword) error {
+	b.staticRoleLock.Lock()
+	defer b.staticRoleLock.Unlock()
+
+	role, err := getStaticRole(ctx, s, entry.Name)
+	if err != nil {
+		return err
+	}
+
+	acr := accounts.NewClient(c.Client)
+
+	if role == nil {
+		// The role was deleted since, and the account is no longer Vault's
+		if entry.PreviousPassword != "" {
+			return nil
+		}
+
+		_, err := acr.SetPassword(ctx, entry.AccountId, entry.NewPassword, 0, accounts.WithAutomaticVersioning(true))
+		if isNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return setStaticRole(ctx, s, &staticRoleEntry{
+			Name:              entry.Name,
+			AccountId:         entry.AccountId,
+			AuthMethodId:      entry.AuthMethodId,
+			LoginName:         entry.LoginName,
+			Password:          entry.NewPassword,
+			RotationPeriod:    time.Duration(entry.RotationPeriod) * time.Second,
+			LastVaultRotation: time.Now(),
+		})
+	}
+
+	if role.AccountId != entry.AccountId || role.Password == entry.NewPassword {
+		return nil
+	}
+
+	_, err = acr.SetPassword(ctx, role.AccountId, role.Password, 0, accounts.WithAutomaticVersioning(true))
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
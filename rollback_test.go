@@ -0,0 +1,156 @@
+package boundarysecrets
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscardRejectedWAL(t *testing.T) {
+	ctx := context.Background()
+	s := &logical.InmemStorage{}
+
+	t.Run("Boundary 4xx", func(t *testing.T) {
+		walId, err := framework.PutWAL(ctx, s, walUserKind, &walUser{ScopeId: "global", Name: "vault-user"})
+		require.NoError(t, err)
+
+		createErr := boundaryStatusError(t, http.StatusBadRequest)
+		require.Equal(t, createErr, discardRejectedWAL(ctx, s, walId, createErr))
+
+		wal, err := framework.GetWAL(ctx, s, walId)
+		require.NoError(t, err)
+		require.Nil(t, wal)
+	})
+
+	t.Run("Boundary 5xx", func(t *testing.T) {
+		walId, err := framework.PutWAL(ctx, s, walUserKind, &walUser{ScopeId: "global", Name: "vault-user"})
+		require.NoError(t, err)
+
+		createErr := boundaryStatusError(t, http.StatusInternalServerError)
+		require.Equal(t, createErr, discardRejectedWAL(ctx, s, walId, createErr))
+
+		wal, err := framework.GetWAL(ctx, s, walId)
+		require.NoError(t, err)
+		require.NotNil(t, wal)
+	})
+}
+
+// TestWALRollback undoes each kind of WAL entry against a fake
+// Boundary controller.
+func TestWALRollback(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	fake := &fakeBoundary{
+		expiration: time.Now().Add(time.Hour),
+		password:   Password,
+	}
+	configureTestBackend(t, b, s, fake)
+
+	rollback := func(kind string, data map[string]interface{}) error {
+		return b.walRollback(ctx, &logical.Request{Storage: s}, kind, data)
+	}
+
+	t.Run("Account", func(t *testing.T) {
+		fake.listItems = map[string][]string{"/v1/accounts": {"acctpw_1111111111"}}
+		fake.deleted = nil
+
+		err := rollback(walAccountKind, map[string]interface{}{
+			"auth_method_id": authMethodId,
+			"login_name":     "vault-role-test-abcdefgh",
+		})
+		require.NoError(t, err)
+		require.Equal(t, `"/item/attributes/login_name" == "vault-role-test-abcdefgh"`, fake.listFilters["/v1/accounts"])
+		require.Equal(t, []string{"/v1/accounts/acctpw_1111111111"}, fake.deleted)
+	})
+
+	t.Run("User", func(t *testing.T) {
+		fake.listItems = map[string][]string{"/v1/users": {"u_1111111111"}}
+		fake.deleted = nil
+
+		err := rollback(walUserKind, map[string]interface{}{
+			"scope_id": "global",
+			"name":     "vault-role-test-abcdefgh",
+		})
+		require.NoError(t, err)
+		require.Equal(t, `"/item/name" == "vault-role-test-abcdefgh"`, fake.listFilters["/v1/users"])
+		require.Equal(t, []string{"/v1/users/u_1111111111"}, fake.deleted)
+	})
+
+	t.Run("Role", func(t *testing.T) {
+		fake.listItems = map[string][]string{"/v1/roles": {"r_1111111111"}}
+		fake.deleted = nil
+
+		err := rollback(walRoleKind, map[string]interface{}{
+			"scope_id": "global",
+			"name":     "vault-role-test-abcdefgh",
+		})
+		require.NoError(t, err)
+		require.Equal(t, `"/item/name" == "vault-role-test-abcdefgh"`, fake.listFilters["/v1/roles"])
+		require.Equal(t, []string{"/v1/roles/r_1111111111"}, fake.deleted)
+	})
+
+	t.Run("Role Principal", func(t *testing.T) {
+		fake.rolePrincipals = map[string][]string{"r_1111111111": {"u_1111111111", "u_2222222222"}}
+
+		err := rollback(walRolePrincipalKind, map[string]interface{}{
+			"role_id":      "r_1111111111",
+			"principal_id": "u_1111111111",
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"u_2222222222"}, fake.rolePrincipals["r_1111111111"])
+	})
+
+	t.Run("Role Principal - Missing Role", func(t *testing.T) {
+		fake.roles = []string{}
+		defer func() { fake.roles = nil }()
+
+		err := rollback(walRolePrincipalKind, map[string]interface{}{
+			"role_id":      "r_1111111111",
+			"principal_id": "u_1111111111",
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Group Member", func(t *testing.T) {
+		fake.groups = map[string][]string{"g_1111111111": {"u_1111111111", "u_2222222222"}}
+
+		err := rollback(walGroupMemberKind, map[string]interface{}{
+			"group_id":  "g_1111111111",
+			"member_id": "u_1111111111",
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"u_2222222222"}, fake.groups["g_1111111111"])
+	})
+
+	t.Run("Group Member - Missing Group", func(t *testing.T) {
+		err := rollback(walGroupMemberKind, map[string]interface{}{
+			"group_id":  "g_2222222222",
+			"member_id": "u_1111111111",
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Scope", func(t *testing.T) {
+		fake.listItems = map[string][]string{"/v1/scopes": {"o_1111111111"}}
+		fake.deleted = nil
+
+		err := rollback(walScopeKind, map[string]interface{}{
+			"scope_id":    "global",
+			"name":        "vault-scopes",
+			"description": "Generated by Vault for role scopes (abc123)",
+		})
+		require.NoError(t, err)
+		require.Equal(t, `"/item/name" == "vault-scopes" and "/item/description" == "Generated by Vault for role scopes (abc123)"`, fake.listFilters["/v1/scopes"])
+		require.Equal(t, []string{"/v1/scopes/o_1111111111"}, fake.deleted)
+	})
+
+	t.Run("Unknown Kind", func(t *testing.T) {
+		require.Error(t, rollback("unknown", map[string]interface{}{}))
+	})
+}
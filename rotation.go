@@ -0,0 +1,160 @@
+package boundarysecrets
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	rotationStoragePath = "config/rotation"
+
+	// rotationBackoffMin and rotationBackoffMax bound the delay
+	// before a failed scheduled rotation is attempted again.
+	rotationBackoffMin = time.Minute
+	rotationBackoffMax = time.Hour
+)
+
+// rootRotationState tracks when the root credential was last
+// rotated and when the next scheduled rotation is due.
+type rootRotationState struct {
+	LastRotation time.Time `json:"last_rotation"`
+	NextRotation time.Time `json:"next_rotation"`
+	Failures     int       `json:"failures"`
+}
+
+func getRotationState(ctx context.Context, s logical.Storage) (*rootRotationState, error) {
+	entry, err := s.Get(ctx, rotationStoragePath)
+	if err != nil {
+		return nil, err
+	}
+
+	state := new(rootRotationState)
+	if entry == nil {
+		return state, nil
+	}
+
+	if err := entry.DecodeJSON(state); err != nil {
+		return nil, fmt.Errorf("error reading root rotation state: %w", err)
+	}
+
+	return state, nil
+}
+
+func putRotationState(ctx context.Context, s logical.Storage, state *rootRotationState) error {
+	entry, err := logical.StorageEntryJSON(rotationStoragePath, state)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+// parseRotationSchedule parses a standard five field cron expression.
+func parseRotationSchedule(schedule string) (cron.Schedule, error) {
+	return cron.ParseStandard(schedule)
+}
+
+// nextRotation returns when the root credential should next be
+// rotated after from, or the zero time if automatic rotation is
+// not configured.
+func (c *boundaryConfig) nextRotation(from time.Time) (time.Time, error) {
+	switch {
+	case c.RotationSchedule != "":
+		schedule, err := parseRotationSchedule(c.RotationSchedule)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return schedule.Next(from), nil
+	case c.RotationPeriod > 0:
+		return from.Add(c.RotationPeriod), nil
+	default:
+		return time.Time{}, nil
+	}
+}
+
+// rotateRootIfDue is called from the periodic function and rotates
+// the root credential once its scheduled time has passed. Failed
+// rotations are retried with exponential backoff.
+func (b *boundaryBackend) rotateRootIfDue(ctx context.Context, s logical.Storage) error {
+	config, err := getConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if config == nil || (config.RotationPeriod == 0 && config.RotationSchedule == "") {
+		return nil
+	}
+
+	state, err := getRotationState(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	if state.NextRotation.IsZero() {
+		state.NextRotation, err = config.nextRotation(now)
+		if err != nil {
+			return err
+		}
+		return putRotationState(ctx, s, state)
+	}
+
+	if now.Before(state.NextRotation) {
+		return nil
+	}
+
+	if err := b.rotateRootCredentials(ctx, s); err != nil {
+		state.Failures++
+
+		backoff := rotationBackoffMin
+		for i := 1; i < state.Failures && backoff < rotationBackoffMax; i++ {
+			backoff *= 2
+		}
+		if backoff > rotationBackoffMax {
+			backoff = rotationBackoffMax
+		}
+		state.NextRotation = now.Add(backoff)
+
+		b.Logger().Error("scheduled root credential rotation failed", "error", err, "failures", state.Failures, "retry_at", state.NextRotation)
+
+		return putRotationState(ctx, s, state)
+	}
+
+	b.Logger().Info("rotated root credential")
+
+	return nil
+}
+
+// periodicFunc runs the backend's scheduled work. Vault calls it
+// roughly once a minute on the active node.
+func (b *boundaryBackend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	replState := b.System().ReplicationState()
+	if (!b.System().LocalMount() && replState.HasState(consts.ReplicationPerformanceSecondary)) ||
+		replState.HasState(consts.ReplicationPerformanceStandby) {
+		return nil
+	}
+
+	// Every step runs even if an earlier one failed
+	var merr *multierror.Error
+
+	if err := b.rotateRootIfDue(ctx, req.Storage); err != nil {
+		merr = multierror.Append(merr, err)
+	}
+
+	if err := b.rotateStaticRolesIfDue(ctx, req.Storage); err != nil {
+		merr = multierror.Append(merr, err)
+	}
+
+	if err := b.deleteUnactivatedWorkers(ctx, req.Storage); err != nil {
+		merr = multierror.Append(merr, err)
+	}
+
+	return merr.ErrorOrNil()
+}
@@ -0,0 +1,111 @@
+package boundarysecrets
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// TestScheduledRootRotation checks that the periodic function
+// rotates the root password once it is due.
+func TestScheduledRootRotation(t *testing.T) {
+	b, reqStorage := getTestBackend(t)
+	ctx := context.Background()
+
+	fake := &fakeBoundary{
+		expiration: time.Now().Add(time.Hour),
+		password:   Password,
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	t.Run("Invalid Settings", func(t *testing.T) {
+		err := testConfigCreate(t, b, reqStorage, map[string]interface{}{
+			"login_name":        loginName,
+			"password":          Password,
+			"addr":              srv.URL,
+			"auth_method_id":    authMethodId,
+			"rotation_period":   "1h",
+			"rotation_schedule": "0 * * * *",
+		})
+		require.Error(t, err)
+
+		err = testConfigCreate(t, b, reqStorage, map[string]interface{}{
+			"login_name":        loginName,
+			"password":          Password,
+			"addr":              srv.URL,
+			"auth_method_id":    authMethodId,
+			"rotation_schedule": "not a schedule",
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("Schedule Rotation", func(t *testing.T) {
+		err := testConfigCreate(t, b, reqStorage, map[string]interface{}{
+			"login_name":      loginName,
+			"password":        Password,
+			"addr":            srv.URL,
+			"auth_method_id":  authMethodId,
+			"rotation_period": "1h",
+		})
+		require.NoError(t, err)
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      configStoragePath,
+			Storage:   reqStorage,
+		})
+		require.NoError(t, err)
+		require.Equal(t, float64(3600), resp.Data["rotation_period"])
+		require.NotNil(t, resp.Data["next_rotation"])
+		require.Nil(t, resp.Data["last_rotation"])
+	})
+
+	t.Run("Not Yet Due", func(t *testing.T) {
+		require.NoError(t, b.periodicFunc(ctx, &logical.Request{Storage: reqStorage}))
+
+		config, err := getConfig(ctx, reqStorage)
+		require.NoError(t, err)
+		require.Equal(t, Password, config.Password)
+	})
+
+	t.Run("Rotate When Due", func(t *testing.T) {
+		state, err := getRotationState(ctx, reqStorage)
+		require.NoError(t, err)
+		state.NextRotation = time.Now().Add(-time.Minute)
+		require.NoError(t, putRotationState(ctx, reqStorage, state))
+
+		require.NoError(t, b.periodicFunc(ctx, &logical.Request{Storage: reqStorage}))
+
+		config, err := getConfig(ctx, reqStorage)
+		require.NoError(t, err)
+		require.NotEqual(t, Password, config.Password)
+		require.Equal(t, fake.password, config.Password)
+
+		state, err = getRotationState(ctx, reqStorage)
+		require.NoError(t, err)
+		require.False(t, state.LastRotation.IsZero())
+		require.WithinDuration(t, time.Now().Add(time.Hour), state.NextRotation, time.Minute)
+	})
+
+	t.Run("Back Off After Failure", func(t *testing.T) {
+		srv.Close()
+
+		state, err := getRotationState(ctx, reqStorage)
+		require.NoError(t, err)
+		state.NextRotation = time.Now().Add(-time.Minute)
+		require.NoError(t, putRotationState(ctx, reqStorage, state))
+		b.reset()
+
+		require.NoError(t, b.periodicFunc(ctx, &logical.Request{Storage: reqStorage}))
+
+		state, err = getRotationState(ctx, reqStorage)
+		require.NoError(t, err)
+		require.Equal(t, 1, state.Failures)
+		require.WithinDuration(t, time.Now().Add(rotationBackoffMin), state.NextRotation, 10*time.Second)
+	})
+}
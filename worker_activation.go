@@ -0,0 +1,160 @@
+package boundarysecrets
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/boundary/api/workers"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const pendingWorkerStoragePrefix = "worker-activation/"
+
+// pendingWorkerEntry tracks a controller-led worker that has not
+// yet been seen to activate. Vault cannot revoke its own leases,
+// so a worker deleted for missing its deadline keeps the entry,
+// marked as deleted, until the lease is revoked. This stops the
+// lease being renewed in the meantime.
+type pendingWorkerEntry struct {
+	WorkerId string    `json:"worker_id"`
+	Deadline time.Time `json:"deadline"`
+	Deleted  bool      `json:"deleted"`
+}
+
+func getPendingWorker(ctx context.Context, s logical.Storage, workerId string) (*pendingWorkerEntry, error) {
+	entry, err := s.Get(ctx, pendingWorkerStoragePrefix+workerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var pending pendingWorkerEntry
+
+	if err := entry.DecodeJSON(&pending); err != nil {
+		return nil, err
+	}
+	return &pending, nil
+}
+
+func setPendingWorker(ctx context.Context, s logical.Storage, pending *pendingWorkerEntry) error {
+	entry, err := logical.StorageEntryJSON(pendingWorkerStoragePrefix+pending.WorkerId, pending)
+	if err != nil {
+		return err
+	}
+
+	if entry == nil {
+		return fmt.Errorf("failed to create storage entry for pending worker")
+	}
+
+	return s.Put(ctx, entry)
+}
+
+// trackWorkerActivation records a new worker so that it can be
+// deleted if it does not activate within the role's activation
+// timeout. Without the entry the worker would outlive its timeout,
+// so it is deleted if the entry cannot be stored.
+func (b *boundaryBackend) trackWorkerActivation(ctx context.Context, s logical.Storage, role *boundaryRoleEntry, workerId string) error {
+	if role.ActivationTimeout <= 0 {
+		return nil
+	}
+
+	err := setPendingWorker(ctx, s, &pendingWorkerEntry{
+		WorkerId: workerId,
+		Deadline: time.Now().Add(role.ActivationTimeout),
+	})
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("error storing pending worker: %w", err)
+
+	client, cerr := b.getClient(ctx, s)
+	if cerr != nil {
+		return fmt.Errorf("%w; error getting client: %v", err, cerr)
+	}
+	if derr := deleteWorker(ctx, b.Logger(), client, workerId); derr != nil {
+		return fmt.Errorf("%w; error deleting worker: %v", err, derr)
+	}
+
+	return err
+}
+
+// deleteUnactivatedWorkers deletes every pending worker that has not
+// reported to a controller by its deadline. Workers that have are
+// no longer tracked. A failure with one worker is logged and retried
+// on the next run rather than stopping the other workers being
+// checked. An error is only returned if no worker can be checked.
+func (b *boundaryBackend) deleteUnactivatedWorkers(ctx context.Context, s logical.Storage) error {
+	ids, err := s.List(ctx, pendingWorkerStoragePrefix)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	client, err := b.getClient(ctx, s)
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	now := time.Now()
+
+	for _, id := range ids {
+		pending, err := getPendingWorker(ctx, s, id)
+		if err != nil {
+			b.Logger().Error("unable to read pending worker", "worker_id", id, "error", err)
+			continue
+		}
+
+		if pending == nil || pending.Deleted || now.Before(pending.Deadline) {
+			continue
+		}
+
+		// A worker that has since been deleted needs no more checks,
+		// and neither does one that has activated
+		activated, err := workerActivated(ctx, client, pending.WorkerId)
+		if err != nil && !isNotFound(err) {
+			b.Logger().Error("unable to check worker activation", "worker_id", pending.WorkerId, "error", err)
+			continue
+		}
+
+		if activated || err != nil {
+			if err := s.Delete(ctx, pendingWorkerStoragePrefix+id); err != nil {
+				b.Logger().Error("unable to remove pending worker", "worker_id", id, "error", err)
+			}
+			continue
+		}
+
+		if err := deleteWorker(ctx, b.Logger(), client, pending.WorkerId); err != nil {
+			b.Logger().Error("unable to delete unactivated worker", "worker_id", pending.WorkerId, "error", err)
+			continue
+		}
+
+		b.Logger().Info("deleted worker that was not activated in time", "worker_id", pending.WorkerId, "deadline", pending.Deadline)
+
+		// If this is not stored, the next run finds the worker gone
+		// and stops tracking it
+		pending.Deleted = true
+		if err := setPendingWorker(ctx, s, pending); err != nil {
+			b.Logger().Error("unable to store deleted worker", "worker_id", pending.WorkerId, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// workerActivated reports whether the worker has reported its status
+// to a controller, which it does once it has authenticated.
+func workerActivated(ctx context.Context, c *boundaryClient, workerId string) (bool, error) {
+	wrr, err := workers.NewClient(c.Client).Read(ctx, workerId)
+	if err != nil {
+		return false, err
+	}
+
+	return !wrr.Item.LastStatusTime.IsZero(), nil
+}
@@ -0,0 +1,127 @@
+package boundarysecrets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeleteUnactivatedWorkers checks that a worker which misses its
+// activation deadline is deleted, and its lease can no longer be
+// renewed.
+func TestDeleteUnactivatedWorkers(t *testing.T) {
+	ctx := context.Background()
+
+	b, s := getTestBackend(t)
+
+	fake := &fakeBoundary{expiration: time.Now().Add(time.Hour)}
+	configureTestBackend(t, b, s, fake)
+
+	_, err := testTokenRoleCreate(t, b, s, workerRoleName, map[string]interface{}{
+		"scope_id":           scope_id,
+		"role_type":          "worker",
+		"ttl":                testTTL,
+		"activation_timeout": "10m",
+	})
+	require.NoError(t, err)
+
+	resp, err := testWorkerCreds(t, b, s, map[string]interface{}{"worker_name": "worker"})
+	require.NoError(t, err)
+	require.False(t, resp.IsError())
+
+	pending, err := getPendingWorker(ctx, s, "w_1234567890")
+	require.NoError(t, err)
+	require.NotNil(t, pending)
+	require.WithinDuration(t, time.Now().Add(10*time.Minute), pending.Deadline, time.Minute)
+
+	t.Run("Renew", func(t *testing.T) {
+		renew, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.RenewOperation,
+			Secret:    resp.Secret,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.False(t, renew.IsError())
+		require.Equal(t, time.Duration(testTTL)*time.Second, renew.Secret.TTL)
+	})
+
+	t.Run("Before Deadline", func(t *testing.T) {
+		require.NoError(t, b.deleteUnactivatedWorkers(ctx, s))
+		require.Empty(t, fake.deleted)
+	})
+
+	t.Run("After Deadline", func(t *testing.T) {
+		pending.Deadline = time.Now().Add(-time.Second)
+		require.NoError(t, setPendingWorker(ctx, s, pending))
+
+		require.NoError(t, b.deleteUnactivatedWorkers(ctx, s))
+		require.Equal(t, []string{"/v1/workers/w_1234567890"}, fake.deleted)
+
+		renew, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.RenewOperation,
+			Secret:    resp.Secret,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.True(t, renew.IsError())
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		fake.missing = []string{"/v1/workers/w_1234567890"}
+
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.RevokeOperation,
+			Secret:    resp.Secret,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+
+		pending, err := getPendingWorker(ctx, s, "w_1234567890")
+		require.NoError(t, err)
+		require.Nil(t, pending)
+	})
+
+	t.Run("Activated", func(t *testing.T) {
+		fake.activated = []string{"w_0987654321"}
+		require.NoError(t, setPendingWorker(ctx, s, &pendingWorkerEntry{
+			WorkerId: "w_0987654321",
+			Deadline: time.Now().Add(-time.Second),
+		}))
+
+		require.NoError(t, b.deleteUnactivatedWorkers(ctx, s))
+		require.NotContains(t, fake.deleted, "/v1/workers/w_0987654321")
+
+		pending, err := getPendingWorker(ctx, s, "w_0987654321")
+		require.NoError(t, err)
+		require.Nil(t, pending)
+	})
+
+	t.Run("Storage Failure", func(t *testing.T) {
+		fake.deleted = nil
+		for _, id := range []string{"w_1111111111", "w_2222222222"} {
+			require.NoError(t, setPendingWorker(ctx, s, &pendingWorkerEntry{
+				WorkerId: id,
+				Deadline: time.Now().Add(-time.Second),
+			}))
+		}
+
+		// Marking the first worker deleted fails, which must not stop
+		// the second from being deleted
+		failing := &failingStorage{Storage: s, key: pendingWorkerStoragePrefix + "w_1111111111"}
+		require.NoError(t, b.deleteUnactivatedWorkers(ctx, failing))
+		require.Equal(t, []string{"/v1/workers/w_1111111111", "/v1/workers/w_2222222222"}, fake.deleted)
+	})
+
+	t.Run("Pending Worker Not Stored", func(t *testing.T) {
+		fake.deleted = nil
+		fake.missing = nil
+
+		failing := &failingStorage{Storage: s, key: pendingWorkerStoragePrefix + "w_1234567890"}
+		_, err := testWorkerCreds(t, b, failing, map[string]interface{}{"worker_name": "worker"})
+		require.Error(t, err)
+		require.Equal(t, []string{"/v1/workers/w_1234567890"}, fake.deleted)
+	})
+}
@@ -0,0 +1,88 @@
+package boundarysecrets
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+)
+
+// anyWorkerTagValue in allowed_worker_tags allows any value for a key.
+const anyWorkerTagValue = "*"
+
+// parseWorkerTags converts a map field into worker tags. Each value
+// may be a list or a comma separated string.
+func parseWorkerTags(raw map[string]interface{}) (map[string][]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	tags := make(map[string][]string, len(raw))
+	for key, value := range raw {
+		if key == "" {
+			return nil, fmt.Errorf("worker tag keys cannot be empty")
+		}
+
+		values, err := parseutil.ParseCommaStringSlice(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid values for worker tag %q: %w", key, err)
+		}
+
+		values = strutil.RemoveDuplicatesStable(values, false)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("worker tag %q has no values", key)
+		}
+
+		tags[key] = values
+	}
+
+	return tags, nil
+}
+
+// checkWorkerTags returns an error unless every requested tag is
+// allowed by the role's allowed_worker_tags.
+func (r *boundaryRoleEntry) checkWorkerTags(requested map[string][]string) error {
+	keys := make([]string, 0, len(requested))
+	for key := range requested {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		allowed, ok := r.AllowedWorkerTags[key]
+		if !ok {
+			return fmt.Errorf("worker tag %q is not allowed by role %q", key, r.Name)
+		}
+
+		if strutil.StrListContains(allowed, anyWorkerTagValue) {
+			continue
+		}
+
+		for _, value := range requested[key] {
+			if !strutil.StrListContains(allowed, value) {
+				return fmt.Errorf("value %q of worker tag %q is not allowed by role %q", value, key, r.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergeWorkerTags combines the role's worker tags with those
+// requested for a single worker.
+func mergeWorkerTags(roleTags map[string][]string, requested map[string][]string) map[string][]string {
+	if len(roleTags) == 0 && len(requested) == 0 {
+		return nil
+	}
+
+	tags := make(map[string][]string, len(roleTags)+len(requested))
+	for key, values := range roleTags {
+		tags[key] = append([]string(nil), values...)
+	}
+	for key, values := range requested {
+		tags[key] = strutil.RemoveDuplicatesStable(append(tags[key], values...), false)
+	}
+
+	return tags
+}
@@ -0,0 +1,92 @@
+package boundarysecrets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckWorkerTags(t *testing.T) {
+	role := &boundaryRoleEntry{
+		Name: "worker",
+		AllowedWorkerTags: map[string][]string{
+			"region": {"eu-west-1", "eu-west-2"},
+			"team":   {anyWorkerTagValue},
+		},
+	}
+
+	require.NoError(t, role.checkWorkerTags(nil))
+	require.NoError(t, role.checkWorkerTags(map[string][]string{
+		"region": {"eu-west-2"},
+		"team":   {"platform", "data"},
+	}))
+	require.Error(t, role.checkWorkerTags(map[string][]string{"region": {"us-east-1"}}))
+	require.Error(t, role.checkWorkerTags(map[string][]string{"env": {"prod"}}))
+}
+
+// TestWorkerTags checks that a worker gets the role's tags along
+// with those the caller is allowed to add.
+func TestWorkerTags(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	fake := &fakeBoundary{expiration: time.Now().Add(time.Hour)}
+	configureTestBackend(t, b, s, fake)
+
+	_, err := testTokenRoleCreate(t, b, s, workerRoleName, map[string]interface{}{
+		"scope_id":            scope_id,
+		"role_type":           "worker",
+		"worker_tags":         map[string]interface{}{"type": "vault,ephemeral"},
+		"allowed_worker_tags": map[string]interface{}{"region": []interface{}{"eu-west-1", "eu-west-2"}},
+	})
+	require.NoError(t, err)
+
+	t.Run("Read Role Tags", func(t *testing.T) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "role/" + workerRoleName,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string][]string{"type": {"vault", "ephemeral"}}, resp.Data["worker_tags"])
+		require.Equal(t, map[string][]string{"region": {"eu-west-1", "eu-west-2"}}, resp.Data["allowed_worker_tags"])
+	})
+
+	t.Run("Tags Not Allowed", func(t *testing.T) {
+		resp, err := testWorkerCreds(t, b, s, map[string]interface{}{
+			"worker_name": "worker",
+			"worker_tags": map[string]interface{}{"region": "us-east-1"},
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		require.Nil(t, fake.workerTags)
+	})
+
+	t.Run("Tags Added", func(t *testing.T) {
+		resp, err := testWorkerCreds(t, b, s, map[string]interface{}{
+			"worker_name": "worker",
+			"worker_tags": map[string]interface{}{"region": "eu-west-1"},
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+
+		expected := map[string][]string{
+			"type":   {"vault", "ephemeral"},
+			"region": {"eu-west-1"},
+		}
+		require.Equal(t, expected, fake.workerTags)
+		require.Equal(t, expected, resp.Data["worker_tags"])
+	})
+}
+
+func testWorkerCreds(t *testing.T, b *boundaryBackend, s logical.Storage, d map[string]interface{}) (*logical.Response, error) {
+	t.Helper()
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "creds/" + workerRoleName,
+		Data:      d,
+		Storage:   s,
+	})
+}